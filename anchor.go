@@ -0,0 +1,152 @@
+package zedit
+
+// Anchor is a position in the text that is updated when the text is edited, like the start of a tag
+// interval. Anchors can be used for implementing bookmarks, breakpoints, and similar features.
+type Anchor struct {
+	editor *Editor
+	pos    CharPos
+	line   bool // anchors the start of a line, see createLineAnchor
+}
+
+// anchorEdit describes an edit within a region of paragraphs for adjusting the anchors in it. The
+// removed runes at the given offset from the start of the region are replaced by the inserted ones.
+// Offsets count all runes except soft line feeds, so they do not depend on word wrapping.
+type anchorEdit struct {
+	offset, removed, inserted int
+}
+
+// savedAnchor is the position of an anchor before an edit, either as offset from the start of the
+// edited region or, if it is behind the region, as line counted from the end of the text.
+type savedAnchor struct {
+	anchor   *Anchor
+	offset   int
+	extent   int // the length of the line of a line anchor
+	fromEnd  int
+	inRegion bool
+}
+
+// CreateAnchor returns a new anchor at the given position. The anchor must be removed with Release
+// or RemoveAnchor when it is no longer needed.
+func (z *Editor) CreateAnchor(pos CharPos) *Anchor {
+	pos = MinPos(CharPos{Line: max(pos.Line, 0), Column: max(pos.Column, 0)}, z.LastPos())
+	a := Anchor{editor: z, pos: pos}
+	z.anchors[&a] = struct{}{}
+	return &a
+}
+
+// createLineAnchor returns a new anchor at the start of the given line. Unlike other anchors, it
+// stays at the start of the line when text is inserted there, e.g., it moves down along with the
+// line when a line break is inserted at its start, and it is only removed when the whole line is
+// deleted. If the start of the line is deleted, it anchors the line that contains the rest of it.
+func (z *Editor) createLineAnchor(line int) *Anchor {
+	a := z.CreateAnchor(CharPos{Line: line, Column: 0})
+	a.line = true
+	return a
+}
+
+// Pos returns the current position of the anchor and true, or the last known position and false if
+// the anchor has been released, the text at its position has been deleted, or the text was replaced.
+func (a *Anchor) Pos() (CharPos, bool) {
+	_, ok := a.editor.anchors[a]
+	return a.pos, ok
+}
+
+// Release removes the anchor from the editor. Its position is no longer updated afterwards.
+func (a *Anchor) Release() {
+	delete(a.editor.anchors, a)
+}
+
+// RemoveAnchor detaches the anchor from the editor like Release.
+func (z *Editor) RemoveAnchor(a *Anchor) {
+	if a != nil && a.editor == z {
+		a.Release()
+	}
+}
+
+// setPos moves the anchor to the given position.
+func (a *Anchor) setPos(pos CharPos) {
+	if _, ok := a.editor.anchors[a]; ok {
+		a.pos = pos
+	}
+}
+
+// saveAnchors returns the positions of the anchors from startRow on, relative to the region from
+// startRow to endRow that is about to be edited. Anchors before the region are not affected.
+func (z *Editor) saveAnchors(startRow, endRow int) []savedAnchor {
+	var saved []savedAnchor
+	for a := range z.anchors {
+		switch {
+		case a.pos.Line < startRow:
+			continue
+		case a.pos.Line > endRow:
+			saved = append(saved, savedAnchor{anchor: a, fromEnd: len(z.Rows) - a.pos.Line})
+		default:
+			s := savedAnchor{anchor: a, offset: z.regionOffset(startRow, a.pos), inRegion: true}
+			if a.line {
+				s.extent = z.logicalRowLen(a.pos.Line)
+			}
+			saved = append(saved, s)
+		}
+	}
+	return saved
+}
+
+// restoreAnchors sets the positions of the saved anchors after the region starting at startRow has
+// been edited. Anchors within the removed runes are released, except for line anchors whose line
+// was not removed completely.
+func (z *Editor) restoreAnchors(saved []savedAnchor, startRow int, edit anchorEdit) {
+	for _, s := range saved {
+		if !s.inRegion {
+			s.anchor.pos.Line = len(z.Rows) - s.fromEnd
+			continue
+		}
+		offset := s.offset
+		switch {
+		case offset < edit.offset:
+		case offset < edit.offset+edit.removed:
+			if !s.anchor.line || offset+s.extent <= edit.offset+edit.removed {
+				s.anchor.Release()
+				continue
+			}
+			offset = edit.offset
+		case offset > edit.offset || s.anchor.line:
+			offset += edit.inserted - edit.removed
+		}
+		s.anchor.pos = z.regionPos(startRow, offset)
+		if s.anchor.line {
+			s.anchor.pos.Column = 0
+		}
+	}
+}
+
+// regionOffset returns the number of runes from the start of startRow to pos, not counting soft line
+// feeds.
+func (z *Editor) regionOffset(startRow int, pos CharPos) int {
+	offset := pos.Column
+	for i := startRow; i < pos.Line; i++ {
+		offset += z.logicalRowLen(i)
+	}
+	return offset
+}
+
+// regionPos returns the position at the given offset from the start of startRow as computed by
+// regionOffset, or the last position if the offset is beyond the text.
+func (z *Editor) regionPos(startRow int, offset int) CharPos {
+	for i := startRow; i <= z.LastLine(); i++ {
+		n := z.logicalRowLen(i)
+		if offset < n || i == z.LastLine() {
+			return CharPos{Line: i, Column: min(offset, z.LastColumn(i))}
+		}
+		offset -= n
+	}
+	return z.LastPos()
+}
+
+// logicalRowLen returns the length of the given row without a soft line feed.
+func (z *Editor) logicalRowLen(row int) int {
+	r := z.row(row)
+	if k := len(r); k > 0 && r[k-1] == z.Config.SoftLF {
+		return k - 1
+	}
+	return len(r)
+}
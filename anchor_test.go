@@ -0,0 +1,102 @@
+package zedit
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkAnchor fails the test unless the anchor is valid and the text of its line continues with want
+// at its position.
+func checkAnchor(t *testing.T, z *Editor, a *Anchor, want string) {
+	t.Helper()
+	pos, ok := a.Pos()
+	if !ok {
+		t.Fatalf("anchor was removed, want it before %q", want)
+	}
+	if got := string(z.row(pos.Line)[pos.Column:]); !strings.HasPrefix(got, want) {
+		t.Errorf("anchor at %d:%d is before %q, want it before %q", pos.Line, pos.Column, got, want)
+	}
+}
+
+func TestAnchorFollowsEdits(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() {
+		z.SetText("hello world\nsecond line")
+		a := z.CreateAnchor(CharPos{Line: 1, Column: 7})
+		if tags := z.Tags.AllTags(); len(tags) != 0 {
+			t.Errorf("creating an anchor added tags %v", tags)
+		}
+		checkAnchor(t, z, a, "line")
+
+		z.Insert([]rune("ab"), CharPos{Line: 1, Column: 0})
+		checkAnchor(t, z, a, "line")
+		if pos, _ := a.Pos(); pos != (CharPos{Line: 1, Column: 9}) {
+			t.Errorf("anchor at %v after inserting before it, want 1:9", pos)
+		}
+		z.SetCaret(CharPos{Line: 0, Column: 5})
+		z.Return()
+		checkAnchor(t, z, a, "line")
+		if pos, _ := a.Pos(); pos.Line != 2 {
+			t.Errorf("anchor on line %d after inserting a line break above it, want 2", pos.Line)
+		}
+		// joining the lines
+		z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 6}, End: CharPos{Line: 1, Column: 6}})
+		checkAnchor(t, z, a, "line")
+		if pos, _ := a.Pos(); pos.Line != 1 {
+			t.Errorf("anchor on line %d after joining its line with the previous one, want 1", pos.Line)
+		}
+		z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 0}, End: CharPos{Line: 1, Column: 3}})
+		checkAnchor(t, z, a, "line")
+
+		// inserting at the anchor does not move it, like the start of a tag
+		pos, _ := a.Pos()
+		z.Insert([]rune("new "), pos)
+		checkAnchor(t, z, a, "new line")
+
+		// deleting the text at the anchor removes it
+		pos, _ = a.Pos()
+		z.Delete(CharInterval{Start: pos, End: CharPos{Line: pos.Line, Column: pos.Column + 2}})
+		if _, ok := a.Pos(); ok {
+			t.Error("anchor is still valid after the text at its position was deleted")
+		}
+	})
+}
+
+func TestAnchorFollowsWordWrap(t *testing.T) {
+	z := newTestEditor(t, 10, 10)
+	z.Do(func() {
+		z.SetText("aaa bbb ccc ddd eee fff\nnext")
+		var eee, next *Anchor
+		for i := range z.Rows {
+			if j := strings.Index(string(z.Rows[i]), "eee"); j >= 0 {
+				eee = z.CreateAnchor(CharPos{Line: i, Column: j})
+			}
+		}
+		next = z.CreateAnchor(CharPos{Line: z.LastLine(), Column: 0})
+		if eee == nil {
+			t.Fatal("eee not found")
+		}
+		z.Insert([]rune("xxxxx "), CharPos{Line: 0, Column: 0})
+		checkAnchor(t, z, eee, "eee")
+		checkAnchor(t, z, next, "next")
+		z.Delete(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 0, Column: 9}})
+		checkAnchor(t, z, eee, "eee")
+		checkAnchor(t, z, next, "next")
+		z.Config.WrapColumn = 6
+		z.RewrapAll()
+		checkAnchor(t, z, eee, "eee")
+		checkAnchor(t, z, next, "next")
+	})
+}
+
+func TestAnchorsAreReleasedBySetText(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() {
+		z.SetText("abc")
+		a := z.CreateAnchor(CharPos{Line: 0, Column: 1})
+		z.SetText("def")
+		if _, ok := a.Pos(); ok {
+			t.Error("anchor is still valid after the text was replaced")
+		}
+	})
+}
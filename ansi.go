@@ -0,0 +1,202 @@
+package zedit
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ansiPalette contains the 16 standard and bright ANSI colors.
+var ansiPalette = [16]color.NRGBA{
+	{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+	{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+	{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// ansiRun is a run of text with the same style in ANSI output.
+type ansiRun struct {
+	text  string
+	style Style
+}
+
+// PrintANSI prints a string at the end of the buffer like Print, interpreting ANSI SGR escape
+// sequences. Foreground and background colors in 16-color, 256-color, and truecolor notation as well
+// as bold and italic are supported, and each styled run of text is marked with a style tag obtained
+// by MakeOrGetStyleTag. All other escape sequences are removed from the text.
+func (z *Editor) PrintANSI(s string) {
+	for _, run := range parseANSI(s) {
+		if run.text == "" {
+			continue
+		}
+		if run.style == EmptyStyle {
+			z.Print(run.text, nil)
+			continue
+		}
+		start := z.CreateAnchor(z.LastPos())
+		z.Print(run.text, nil)
+		end, _ := z.PrevPos(z.LastPos())
+		if pos, ok := start.Pos(); ok && CmpPos(pos, end) <= 0 {
+			z.Tags.Add(CharInterval{Start: pos, End: end}, z.MakeOrGetStyleTag(run.style, false))
+		}
+		start.Release()
+	}
+	z.Refresh()
+}
+
+// PrintANSI_Sync is like PrintANSI but holds the editor's edit lock, so it may be called from a
+// background goroutine.
+func (z *Editor) PrintANSI_Sync(s string) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	z.PrintANSI(s)
+}
+
+// parseANSI splits s into runs of plain text with their styles according to the SGR escape
+// sequences in s. Other escape sequences are dropped.
+func parseANSI(s string) []ansiRun {
+	var runs []ansiRun
+	var sb strings.Builder
+	style := EmptyStyle
+	flush := func() {
+		if sb.Len() > 0 {
+			runs = append(runs, ansiRun{text: sb.String(), style: style})
+			sb.Reset()
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\x1b' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			break
+		}
+		switch s[i+1] {
+		case '[':
+			// CSI: parameter and intermediate bytes followed by a final byte in 0x40-0x7e
+			j := i + 2
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			if j >= len(s) {
+				i = len(s)
+				break
+			}
+			if s[j] == 'm' {
+				flush()
+				style = applySGR(style, s[i+2:j])
+			}
+			i = j
+		case ']':
+			// OSC: terminated by BEL or ESC backslash
+			j := i + 2
+			for j < len(s) && s[j] != '\a' && !(s[j] == '\x1b' && j+1 < len(s) && s[j+1] == '\\') {
+				j++
+			}
+			if j < len(s) && s[j] == '\x1b' {
+				j++
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	flush()
+	return runs
+}
+
+// applySGR returns the style modified by the semicolon-separated SGR parameters.
+func applySGR(style Style, params string) Style {
+	var codes []int
+	for _, p := range strings.Split(params, ";") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			n = 0
+		}
+		codes = append(codes, n)
+	}
+	for i := 0; i < len(codes); i++ {
+		c := codes[i]
+		switch {
+		case c == 0:
+			style = EmptyStyle
+		case c == 1:
+			style.Bold = true
+		case c == 3:
+			style.Italic = true
+		case c == 22:
+			style.Bold = false
+		case c == 23:
+			style.Italic = false
+		case c >= 30 && c <= 37:
+			style.FGColor = ansiPalette[c-30]
+		case c >= 90 && c <= 97:
+			style.FGColor = ansiPalette[c-90+8]
+		case c == 39:
+			style.FGColor = nil
+		case c >= 40 && c <= 47:
+			style.BGColor = ansiPalette[c-40]
+		case c >= 100 && c <= 107:
+			style.BGColor = ansiPalette[c-100+8]
+		case c == 49:
+			style.BGColor = nil
+		case c == 38 || c == 48:
+			col, n := ansiExtendedColor(codes[i+1:])
+			i += n
+			if col == nil {
+				continue
+			}
+			if c == 38 {
+				style.FGColor = col
+			} else {
+				style.BGColor = col
+			}
+		}
+	}
+	return style
+}
+
+// ansiExtendedColor parses the arguments of an extended color code, which are either 5;n for a
+// 256-color palette index or 2;r;g;b for a truecolor. It returns the color, or nil if the arguments
+// are invalid, and the number of arguments consumed.
+func ansiExtendedColor(args []int) (color.Color, int) {
+	if len(args) == 0 {
+		return nil, 0
+	}
+	switch args[0] {
+	case 5:
+		if len(args) < 2 {
+			return nil, len(args)
+		}
+		return ansi256Color(args[1]), 2
+	case 2:
+		if len(args) < 4 {
+			return nil, len(args)
+		}
+		return color.NRGBA{R: uint8(args[1]), G: uint8(args[2]), B: uint8(args[3]), A: 255}, 4
+	}
+	return nil, 1
+}
+
+// ansi256Color returns the color with the given index in the xterm 256-color palette, which consists
+// of the 16 ANSI colors, a 6x6x6 color cube, and 24 shades of gray.
+func ansi256Color(n int) color.Color {
+	switch {
+	case n < 0 || n > 255:
+		return nil
+	case n < 16:
+		return ansiPalette[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		return color.NRGBA{R: level(n / 36), G: level(n / 6 % 6), B: level(n % 6), A: 255}
+	}
+	gray := uint8(8 + (n-232)*10)
+	return color.NRGBA{R: gray, G: gray, B: gray, A: 255}
+}
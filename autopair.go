@@ -0,0 +1,94 @@
+package zedit
+
+import "unicode"
+
+// autoPairTagName is the name of the tags tracking automatically inserted pairs of quotes, whose
+// interval starts at the opening and ends at the closing quote.
+const autoPairTagName = "_autopair"
+
+// IsAutoPairQuote returns true if the rune is a quotation mark that is paired automatically if
+// Config.AutoPairQuotes is true.
+func IsAutoPairQuote(r rune) bool {
+	switch r {
+	case '"', '\'', '`':
+		return true
+	}
+	return false
+}
+
+// maybeAutoPair handles a typed quotation mark if Config.AutoPairQuotes is true. If the caret is on
+// the closing quote of an automatically inserted pair, typing the same quote moves the caret over it.
+// Otherwise, a pair of quotes is inserted and the caret is put between them, unless the quote follows a
+// letter or digit like an apostrophe in "don't". It returns true if the rune was handled.
+func (z *Editor) maybeAutoPair(r rune) bool {
+	if !z.Config.AutoPairQuotes || !IsAutoPairQuote(r) {
+		return false
+	}
+	if tag, interval, ok := z.autoPairAt(z.caretPos); ok && interval.End == z.caretPos {
+		if c, _ := z.CharAt(z.caretPos); c == r {
+			z.Tags.Delete(tag)
+			z.MoveCaret(CaretRight)
+			return true
+		}
+	}
+	pos := z.caretPos
+	if prev, ok := z.PrevPos(pos); ok {
+		if c, _ := z.CharAt(prev); unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return false
+		}
+	}
+	z.Insert([]rune{r, r}, pos)
+	z.SetCaret(pos)
+	z.MoveCaret(CaretRight)
+	z.Tags.Upsert(z.Tags.CloneTag(NewTag(autoPairTagName)), CharInterval{Start: pos, End: z.caretPos})
+	return true
+}
+
+// maybeDeleteAutoPair deletes both quotes of an empty automatically inserted pair if the caret is
+// between them. It returns true if the pair was deleted.
+func (z *Editor) maybeDeleteAutoPair() bool {
+	if !z.Config.AutoPairQuotes {
+		return false
+	}
+	tag, interval, ok := z.autoPairAt(z.caretPos)
+	if !ok || interval.End != z.caretPos {
+		return false
+	}
+	if prev, ok := z.PrevPos(z.caretPos); !ok || prev != interval.Start {
+		return false
+	}
+	z.Tags.Delete(tag)
+	z.Delete(interval)
+	return true
+}
+
+// autoPairAt returns the innermost automatically inserted pair containing pos whose quotes are still
+// intact. Pairs whose quotes have been changed or deleted are removed.
+func (z *Editor) autoPairAt(pos CharPos) (Tag, CharInterval, bool) {
+	tags, ok := z.Tags.LookupRange(CharInterval{Start: pos, End: pos})
+	if !ok {
+		return nil, CharInterval{}, false
+	}
+	var found Tag
+	var foundInterval CharInterval
+	for _, tag := range tags {
+		if tag == nil || tag.Name() != autoPairTagName {
+			continue
+		}
+		interval, ok := z.Tags.Lookup(tag)
+		if !ok {
+			continue
+		}
+		open, ok1 := z.CharAt(interval.Start)
+		closing, ok2 := z.CharAt(interval.End)
+		if !ok1 || !ok2 || open != closing || !IsAutoPairQuote(open) {
+			z.Tags.Delete(tag)
+			continue
+		}
+		if found == nil || CmpPos(interval.Start, foundInterval.Start) > 0 {
+			found = tag
+			foundInterval = interval
+		}
+	}
+	return found, foundInterval, found != nil
+}
@@ -0,0 +1,41 @@
+package zedit
+
+import "testing"
+
+func TestAutoPairQuotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		typed string
+		want  string
+	}{
+		{"empty text", "", `"`, `""`},
+		{"after space", "a ", `'`, `a ''`},
+		{"after bracket", "f(", "`", "f(``"},
+		{"apostrophe after letter", "don", "'t", "don't"},
+		{"quote after digit", "12", `"`, `12"`},
+		{"quote after non-ASCII letter", "ça", `'`, `ça'`},
+		{"typing over closing quote", "", `""`, `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newTestEditor(t, 80, 10)
+			z.Config.AutoPairQuotes = true
+			var want string
+			z.Do(func() {
+				z.SetText(tt.want)
+				want = z.Text()
+				z.SetText(tt.text)
+				z.SetCaret(CharPos{Line: 0, Column: len([]rune(tt.text))})
+			})
+			for _, r := range tt.typed {
+				z.TypedRune(r)
+			}
+			var got string
+			z.Do(func() { got = z.Text() })
+			if got != want {
+				t.Errorf("text = %q, want %q", got, want)
+			}
+		})
+	}
+}
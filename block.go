@@ -0,0 +1,116 @@
+package zedit
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// SelectBlock selects the rectangle of columns between the columns of from and to, inclusive, on all
+// lines between from and to. Lines that end before the left column of the rectangle are not part
+// of the selection and line endings are never selected. The first line of the block is marked with
+// Config.SelectionTag and the other lines with clones of it, so Selections returns one interval per
+// line. Typing, Backspace, and Delete apply to each line of a block selection.
+func (z *Editor) SelectBlock(from, to CharPos) {
+	z.RemoveSelection()
+	z.setBlockSelection(from, to)
+	z.Refresh()
+	z.handleSelectionChange()
+}
+
+// IsBlockSelection returns true if the current selection is a block selection.
+func (z *Editor) IsBlockSelection() bool {
+	return z.blockSelection
+}
+
+// setBlockSelection marks the block between from and to as selection, replacing any current
+// selection, but leaves the drag state untouched.
+func (z *Editor) setBlockSelection(from, to CharPos) {
+	z.clearBlockTags()
+	z.Tags.Delete(z.Config.SelectionTag)
+	top, bottom := min(from.Line, to.Line), min(max(from.Line, to.Line), z.LastLine())
+	left, right := min(from.Column, to.Column), max(from.Column, to.Column)
+	first := true
+	for line := max(top, 0); line <= bottom; line++ {
+		last := z.LastColumn(line) - 1
+		if left > last {
+			continue
+		}
+		interval := CharInterval{Start: CharPos{Line: line, Column: left}, End: CharPos{Line: line, Column: min(right, last)}}
+		if first {
+			z.Tags.Upsert(z.Config.SelectionTag, interval)
+			first = false
+			continue
+		}
+		tag := z.Tags.CloneTag(z.Config.SelectionTag)
+		z.Tags.Upsert(tag, interval)
+		z.blockTags = append(z.blockTags, tag)
+	}
+	z.blockSelection = true
+}
+
+// clearBlockTags removes the tags marking the lines of a block selection except for the first line.
+func (z *Editor) clearBlockTags() {
+	for _, tag := range z.blockTags {
+		z.Tags.Delete(tag)
+	}
+	z.blockTags = nil
+	z.blockSelection = false
+}
+
+// blockIntervals returns the intervals of the lines of the block selection and removes the selection.
+// It returns nil if there is no block selection.
+func (z *Editor) blockIntervals() []CharInterval {
+	if !z.blockSelection {
+		return nil
+	}
+	sels := z.Selections()
+	z.RemoveSelection()
+	return sels
+}
+
+// deleteBlockSelection deletes the text of each line of the block selection and puts the caret at the
+// start of the first line. It returns false if there is no block selection.
+func (z *Editor) deleteBlockSelection() bool {
+	sels := z.blockIntervals()
+	if sels == nil {
+		return false
+	}
+	for i := len(sels) - 1; i >= 0; i-- {
+		z.Delete(sels[i])
+	}
+	if len(sels) > 0 {
+		z.SetCaret(sels[0].Start)
+	}
+	return true
+}
+
+// typeIntoBlock replaces the text of each line of the block selection by the runes and puts the caret
+// after them on the first line. It returns false if there is no block selection.
+func (z *Editor) typeIntoBlock(r []rune) bool {
+	sels := z.blockIntervals()
+	if sels == nil {
+		return false
+	}
+	z.fillVirtualSpace()
+	var caret CharPos
+	for i := len(sels) - 1; i >= 0; i-- {
+		z.Delete(sels[i])
+		start, composed := z.composeInput(r, sels[i].Start)
+		z.Insert(r, sels[i].Start)
+		caret = z.advancePos(start, len(composed))
+	}
+	if len(sels) > 0 {
+		z.SetCaret(caret)
+	}
+	return true
+}
+
+// currentKeyModifiers returns the modifier keys currently held down, or 0 if the driver cannot tell.
+func currentKeyModifiers() fyne.KeyModifier {
+	if app := fyne.CurrentApp(); app != nil {
+		if drv, ok := app.Driver().(desktop.Driver); ok {
+			return drv.CurrentKeyModifiers()
+		}
+	}
+	return 0
+}
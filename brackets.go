@@ -0,0 +1,350 @@
+package zedit
+
+// selectionState is a selection or the absence of one, as stored by ExpandSelection.
+type selectionState struct {
+	interval CharInterval
+	selected bool
+}
+
+// ExpandSelection grows the selection to the next larger unit containing it: the word under the caret,
+// the contents of the innermost enclosing brackets or quotation marks, the contents including the
+// brackets or quotation marks, and so on for further enclosing brackets, then the paragraphs, and
+// finally the whole text. The previous selection is remembered for ShrinkSelection.
+func (z *Editor) ExpandSelection() {
+	cur := z.currentSelectionState()
+	if len(z.expandStack) > 0 && (!cur.selected || cur.interval != z.expandedTo) {
+		z.expandStack = nil
+	}
+	next, ok := z.nextSelectionUnit(cur)
+	if !ok {
+		return
+	}
+	z.expandStack = append(z.expandStack, cur)
+	z.expandedTo = next
+	z.selectUnit(next)
+}
+
+// ShrinkSelection restores the selection before the last ExpandSelection. Nothing is done if the
+// selection has been changed otherwise since then.
+func (z *Editor) ShrinkSelection() {
+	cur := z.currentSelectionState()
+	if len(z.expandStack) == 0 || !cur.selected || cur.interval != z.expandedTo {
+		z.expandStack = nil
+		return
+	}
+	prev := z.expandStack[len(z.expandStack)-1]
+	z.expandStack = z.expandStack[:len(z.expandStack)-1]
+	if !prev.selected {
+		z.RemoveSelection()
+		return
+	}
+	z.expandedTo = prev.interval
+	z.selectUnit(prev.interval)
+}
+
+// currentSelectionState returns the current selection, or the caret position if there is none.
+func (z *Editor) currentSelectionState() selectionState {
+	if sel, ok := z.CurrentSelection(); ok {
+		return selectionState{interval: sel, selected: true}
+	}
+	return selectionState{interval: CharInterval{Start: z.caretPos, End: z.caretPos}}
+}
+
+// selectUnit selects the interval without resetting the expansion state.
+func (z *Editor) selectUnit(interval CharInterval) {
+	z.SetCaret(interval.Start)
+	z.Select(interval)
+	z.scrollToCaret()
+}
+
+// nextSelectionUnit returns the smallest unit that contains the current selection and is larger than
+// it. If there is no selection, a unit only needs to contain the caret.
+func (z *Editor) nextSelectionUnit(cur selectionState) (CharInterval, bool) {
+	grows := func(unit CharInterval) bool {
+		return containsInterval(unit, cur.interval) && (!cur.selected || unit != cur.interval)
+	}
+	if !cur.selected {
+		if word, unit := z.getWordAt(z.caretPos); word != "" {
+			return unit, true
+		}
+	}
+	for inner := cur.interval; ; {
+		openPos, closePos, ok := z.enclosingPair(inner)
+		if !ok {
+			break
+		}
+		if l, ok := z.NextPos(openPos); ok {
+			if r, ok := z.PrevPos(closePos); ok && CmpPos(l, r) <= 0 {
+				if unit := (CharInterval{Start: l, End: r}); grows(unit) {
+					return unit, true
+				}
+			}
+		}
+		if unit := (CharInterval{Start: openPos, End: closePos}); grows(unit) {
+			return unit, true
+		}
+		inner = CharInterval{Start: openPos, End: closePos}
+	}
+	start := z.FindParagraphStart(cur.interval.Start.Line, z.Config.HardLF)
+	end := z.FindParagraphEnd(cur.interval.End.Line, z.Config.HardLF)
+	if unit := (CharInterval{Start: CharPos{Line: start, Column: 0},
+		End: CharPos{Line: end, Column: max(0, z.LastColumn(end)-1)}}); grows(unit) {
+		return unit, true
+	}
+	if unit := (CharInterval{Start: CharPos{Line: 0, Column: 0}, End: z.LastPos()}); grows(unit) {
+		return unit, true
+	}
+	return CharInterval{}, false
+}
+
+// containsInterval returns true if a contains b.
+func containsInterval(a, b CharInterval) bool {
+	return CmpPos(a.Start, b.Start) <= 0 && CmpPos(a.End, b.End) >= 0
+}
+
+// enclosingPair returns the positions of the innermost brackets or quotation marks enclosing the
+// interval. Quotation marks are only considered within the paragraph of the interval.
+func (z *Editor) enclosingPair(interval CharInterval) (CharPos, CharPos, bool) {
+	openPos, closePos, ok := z.enclosingBrackets(interval)
+	qOpen, qClose, qOk := z.enclosingQuotes(interval)
+	if qOk && (!ok || CmpPos(qOpen, openPos) > 0) {
+		return qOpen, qClose, true
+	}
+	return openPos, closePos, ok
+}
+
+// enclosingBrackets returns the positions of the innermost pair of matching brackets enclosing
+// the interval.
+func (z *Editor) enclosingBrackets(interval CharInterval) (CharPos, CharPos, bool) {
+	start, ok := z.PrevPos(interval.Start)
+	if !ok {
+		return CharPos{}, CharPos{}, false
+	}
+	depth := 0
+	openPos, ok := z.findRuneAt(start, true, func(c rune, p CharPos) bool {
+		if !z.shouldMatchBracketAt(p) {
+			return false
+		}
+		if IsRightParen(c) {
+			depth++
+		} else if IsLeftParen(c) {
+			if depth == 0 {
+				return true
+			}
+			depth--
+		}
+		return false
+	})
+	if !ok {
+		return CharPos{}, CharPos{}, false
+	}
+	end, ok := z.NextPos(interval.End)
+	if !ok {
+		return CharPos{}, CharPos{}, false
+	}
+	openRune, _ := z.CharAt(openPos)
+	depth = 0
+	closePos, ok := z.findRuneAt(end, false, func(c rune, p CharPos) bool {
+		if !z.shouldMatchBracketAt(p) {
+			return false
+		}
+		if IsLeftParen(c) {
+			depth++
+		} else if IsRightParen(c) {
+			if depth == 0 {
+				return true
+			}
+			depth--
+		}
+		return false
+	})
+	if !ok {
+		return CharPos{}, CharPos{}, false
+	}
+	if closeRune, _ := z.CharAt(closePos); closeRune != matchingBracket(openRune) {
+		return CharPos{}, CharPos{}, false
+	}
+	return openPos, closePos, true
+}
+
+// enclosingQuotes returns the positions of the nearest unescaped quotation marks of the same kind
+// left and right of the interval within its paragraph.
+func (z *Editor) enclosingQuotes(interval CharInterval) (CharPos, CharPos, bool) {
+	first := CharPos{Line: z.FindParagraphStart(interval.Start.Line, z.Config.HardLF), Column: 0}
+	last := z.FindParagraphEnd(interval.End.Line, z.Config.HardLF)
+	start, ok := z.PrevPos(interval.Start)
+	if !ok || CmpPos(start, first) < 0 {
+		return CharPos{}, CharPos{}, false
+	}
+	openPos, ok := z.findRuneAt(start, true, func(c rune, p CharPos) bool {
+		return CmpPos(p, first) < 0 || (IsQuotationMark(c) && !z.isEscaped(p) && z.shouldMatchBracketAt(p))
+	})
+	if !ok || CmpPos(openPos, first) < 0 {
+		return CharPos{}, CharPos{}, false
+	}
+	quote, _ := z.CharAt(openPos)
+	quotes := 0
+	for p := first; CmpPos(p, openPos) < 0; p, _ = z.NextPos(p) {
+		if c, _ := z.CharAt(p); c == quote && !z.isEscaped(p) && z.shouldMatchBracketAt(p) {
+			quotes++
+		}
+	}
+	if quotes%2 == 1 {
+		// the quotation mark closes a quotation that ends before the interval
+		return CharPos{}, CharPos{}, false
+	}
+	end, ok := z.NextPos(interval.End)
+	if !ok {
+		return CharPos{}, CharPos{}, false
+	}
+	closePos, ok := z.findRuneAt(end, false, func(c rune, p CharPos) bool {
+		return p.Line > last || (c == quote && !z.isEscaped(p) && z.shouldMatchBracketAt(p))
+	})
+	if !ok || closePos.Line > last {
+		return CharPos{}, CharPos{}, false
+	}
+	return openPos, closePos, true
+}
+
+// isEscaped returns true if the rune at pos is preceded by an odd number of backslashes.
+func (z *Editor) isEscaped(pos CharPos) bool {
+	n := 0
+	for {
+		prev, ok := z.PrevPos(pos)
+		if !ok {
+			break
+		}
+		if c, _ := z.CharAt(prev); c != '\\' {
+			break
+		}
+		n++
+		pos = prev
+	}
+	return n%2 == 1
+}
+
+// matchingBracket returns the bracket matching the given bracket, or the rune itself if it is not
+// a bracket.
+func matchingBracket(r rune) rune {
+	switch r {
+	case '(':
+		return ')'
+	case ')':
+		return '('
+	case '[':
+		return ']'
+	case ']':
+		return '['
+	case '{':
+		return '}'
+	case '}':
+		return '{'
+	}
+	return r
+}
+
+// GotoMatchingBracket moves the caret to the bracket or quotation mark matching the one under the
+// caret or, if there is none, the one left of the caret. Quotation marks match the next unescaped
+// quotation mark of the same kind if they open a quotation and the previous one if they close it.
+// If there is no match, the bracket is marked as error paren and the caret is not moved.
+func (z *Editor) GotoMatchingBracket() {
+	_, match, ok := z.matchAtCaret()
+	if !ok {
+		return
+	}
+	z.SetCaret(match)
+	z.scrollToCaret()
+}
+
+// SelectToMatchingBracket selects the range from the bracket or quotation mark next to the caret to
+// its matching counterpart, inclusive, as determined by GotoMatchingBracket.
+func (z *Editor) SelectToMatchingBracket() {
+	pos, match, ok := z.matchAtCaret()
+	if !ok {
+		return
+	}
+	interval := CharInterval{Start: pos, End: match}.MaybeSwap()
+	z.SetCaret(interval.Start)
+	z.Select(interval)
+	z.scrollToCaret()
+}
+
+// matchAtCaret returns the position of the bracket or quotation mark under or left of the caret
+// and the position of its match. If there is a bracket without a match, it is marked as error paren.
+func (z *Editor) matchAtCaret() (CharPos, CharPos, bool) {
+	candidates := []CharPos{z.caretPos}
+	if prev, ok := z.PrevPos(z.caretPos); ok {
+		candidates = append(candidates, prev)
+	}
+	for _, pos := range candidates {
+		r, ok := z.CharAt(pos)
+		if !ok || !(IsLeftParen(r) || IsRightParen(r) || IsQuotationMark(r)) || !z.shouldMatchBracketAt(pos) {
+			continue
+		}
+		if IsQuotationMark(r) && z.isEscaped(pos) {
+			continue
+		}
+		match, ok := z.findMatch(pos, r)
+		if !ok {
+			z.MarkErrorParen(CharInterval{Start: pos, End: pos})
+			z.Refresh()
+			return CharPos{}, CharPos{}, false
+		}
+		return pos, match, true
+	}
+	return CharPos{}, CharPos{}, false
+}
+
+// findMatch returns the position of the bracket or quotation mark matching r at pos.
+func (z *Editor) findMatch(pos CharPos, r rune) (CharPos, bool) {
+	switch {
+	case IsRightParen(r):
+		return z.findLeftMatch(pos, r)
+	case IsLeftParen(r):
+		next, ok := z.NextPos(pos)
+		if !ok {
+			return CharPos{}, false
+		}
+		depth := 0
+		match, ok := z.findRuneAt(next, false, func(c rune, p CharPos) bool {
+			if !z.shouldMatchBracketAt(p) {
+				return false
+			}
+			if IsLeftParen(c) {
+				depth++
+			} else if IsRightParen(c) {
+				if depth == 0 {
+					return true
+				}
+				depth--
+			}
+			return false
+		})
+		if c, _ := z.CharAt(match); !ok || c != matchingBracket(r) {
+			return CharPos{}, false
+		}
+		return match, true
+	}
+	first := CharPos{Line: z.FindParagraphStart(pos.Line, z.Config.HardLF), Column: 0}
+	quotes := 0
+	for p := first; CmpPos(p, pos) < 0; p, _ = z.NextPos(p) {
+		if c, _ := z.CharAt(p); c == r && !z.isEscaped(p) && z.shouldMatchBracketAt(p) {
+			quotes++
+		}
+	}
+	isQuote := func(c rune, p CharPos) bool {
+		return c == r && !z.isEscaped(p) && z.shouldMatchBracketAt(p)
+	}
+	if quotes%2 == 1 {
+		prev, ok := z.PrevPos(pos)
+		if !ok {
+			return CharPos{}, false
+		}
+		return z.findRuneAt(prev, true, isQuote)
+	}
+	next, ok := z.NextPos(pos)
+	if !ok {
+		return CharPos{}, false
+	}
+	return z.findRuneAt(next, false, isQuote)
+}
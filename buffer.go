@@ -0,0 +1,156 @@
+package zedit
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"golang.org/x/exp/slices"
+)
+
+// Buffer provides the paragraphs of a text, without line feeds, for loading them into an editor with
+// SetBuffer. Implementations may read the paragraphs from a file or another source on demand.
+type Buffer interface {
+	LineCount() int    // the number of paragraphs
+	Line(i int) []rune // the runes of paragraph i (0-indexed), which are not modified by the editor
+	RuneCount() int    // the number of runes of all paragraphs, not counting line feeds
+}
+
+// MemBuffer is a Buffer holding the paragraphs of a text in memory.
+type MemBuffer struct {
+	lines [][]rune
+	runes int
+}
+
+// NewMemBuffer returns a new memory buffer holding the given text, whose paragraphs are separated
+// by newlines.
+func NewMemBuffer(s string) *MemBuffer {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	b := &MemBuffer{lines: make([][]rune, len(lines))}
+	for i := range lines {
+		b.lines[i] = []rune(lines[i])
+		b.runes += len(b.lines[i])
+	}
+	return b
+}
+
+// LineCount implements Buffer.
+func (b *MemBuffer) LineCount() int {
+	return len(b.lines)
+}
+
+// Line implements Buffer.
+func (b *MemBuffer) Line(i int) []rune {
+	return b.lines[i]
+}
+
+// RuneCount implements Buffer.
+func (b *MemBuffer) RuneCount() int {
+	return b.runes
+}
+
+// NewEditorWithBuffer returns a new editor like NewEditor whose text is loaded from the given buffer.
+func NewEditorWithBuffer(columns, lines int, c fyne.Canvas, buf Buffer) *Editor {
+	z := NewEditor(columns, lines, c)
+	z.SetBuffer(buf)
+	return z
+}
+
+// bufferPageSize is the number of paragraphs of a custom buffer that are loaded at once.
+const bufferPageSize = 1024
+
+// maxLoadedPages is the number of pages of a custom buffer that are kept in memory. Pages that are
+// displayed or contain the caret are kept even if there are more.
+const maxLoadedPages = 64
+
+// pagedBuffer keeps track of the pages of a custom buffer that have been loaded into the rows of
+// the editor. Rows of pages that have not been loaded or have been evicted are nil.
+type pagedBuffer struct {
+	buf        Buffer
+	pages      []int // loaded pages, least recently loaded first
+	lastColumn int   // the last column of the last row, so that LastPos does not load its page
+}
+
+// SetBuffer replaces the text by the paragraphs of the buffer, removing all tags like SetText.
+// Unlike SetText, the text is not normalized and control characters are kept. The paragraphs of a
+// MemBuffer are copied into the editor at once. The paragraphs of other buffers are read on demand
+// in pages of bufferPageSize paragraphs when they are displayed or accessed, and only a limited number
+// of pages is kept in memory, so that very large texts can be viewed without reading them completely.
+// Such a text is not word wrapped and is read completely as soon as it is edited.
+func (z *Editor) SetBuffer(buf Buffer) {
+	z.Tags.Clear()
+	if _, ok := buf.(*MemBuffer); ok || buf.LineCount() == 0 {
+		z.setParagraphs(max(buf.LineCount(), 1), func(i int) []rune {
+			if i >= buf.LineCount() {
+				return nil
+			}
+			return buf.Line(i)
+		})
+		return
+	}
+	removed := z.runeCount(0, len(z.Rows))
+	z.Rows = make([][]rune, buf.LineCount())
+	z.paged = &pagedBuffer{buf: buf, lastColumn: len(buf.Line(buf.LineCount() - 1))}
+	z.anchors = make(map[*Anchor]struct{})
+	z.highlightAll()
+	z.markChanged()
+	z.notifyChange(Change{Interval: CharInterval{Start: CharPos{Line: 0, Column: 0}, End: z.LastPos()},
+		Inserted: buf.RuneCount() + buf.LineCount(), Removed: removed})
+	z.Refresh()
+}
+
+// row returns the runes of row i including the line feed, loading its page from the custom buffer
+// if necessary. All code that only reads rows must use it instead of accessing Rows directly.
+func (z *Editor) row(i int) []rune {
+	if r := z.Rows[i]; r != nil || z.paged == nil {
+		return r
+	}
+	z.loadPage(i / bufferPageSize)
+	return z.Rows[i]
+}
+
+// loadPage reads the given page of the custom buffer into the rows and evicts the least recently
+// loaded page that is neither displayed nor contains the caret if too many pages are loaded.
+func (z *Editor) loadPage(page int) {
+	p := z.paged
+	for i := page * bufferPageSize; i < min((page+1)*bufferPageSize, len(z.Rows)); i++ {
+		z.Rows[i] = append(slices.Clone(p.buf.Line(i)), z.Config.HardLF)
+	}
+	p.pages = append(p.pages, page)
+	z.maxLineLenValid = false
+	if len(p.pages) <= maxLoadedPages {
+		return
+	}
+	for k, evicted := range p.pages {
+		if evicted == page || z.pinnedPage(evicted) {
+			continue
+		}
+		for i := evicted * bufferPageSize; i < min((evicted+1)*bufferPageSize, len(z.Rows)); i++ {
+			z.Rows[i] = nil
+		}
+		p.pages = slices.Delete(p.pages, k, k+1)
+		return
+	}
+}
+
+// pinnedPage returns true if the given page must not be evicted because it is displayed or contains
+// the caret.
+func (z *Editor) pinnedPage(page int) bool {
+	return page == z.caretPos.Line/bufferPageSize ||
+		(page >= z.lineOffset/bufferPageSize && page <= (z.lineOffset+z.Lines)/bufferPageSize)
+}
+
+// materialize reads all paragraphs of a custom buffer into the rows, so that the text can be edited.
+// The rows are not word wrapped, since this would invalidate the positions the caller is about to
+// edit, but edited paragraphs are wrapped as usual. Nothing is done if all rows are in memory already.
+func (z *Editor) materialize() {
+	if z.paged == nil {
+		return
+	}
+	for i := range z.Rows {
+		if z.Rows[i] == nil {
+			z.Rows[i] = append(slices.Clone(z.paged.buf.Line(i)), z.Config.HardLF)
+		}
+	}
+	z.paged = nil
+	z.maxLineLenValid = false
+}
@@ -0,0 +1,149 @@
+package zedit
+
+import (
+	"fmt"
+	"testing"
+)
+
+// countingBuffer is a custom buffer that generates its lines and records which lines were read.
+type countingBuffer struct {
+	n     int
+	reads map[int]int
+}
+
+func newCountingBuffer(n int) *countingBuffer {
+	return &countingBuffer{n: n, reads: make(map[int]int)}
+}
+
+func (b *countingBuffer) LineCount() int {
+	return b.n
+}
+
+func (b *countingBuffer) Line(i int) []rune {
+	b.reads[i]++
+	return []rune(fmt.Sprintf("line %d", i))
+}
+
+func (b *countingBuffer) RuneCount() int {
+	n := 0
+	for i := 0; i < b.n; i++ {
+		n += len(fmt.Sprintf("line %d", i))
+	}
+	return n
+}
+
+// loadedPages returns the number of pages of the custom buffer that are loaded into the editor.
+func loadedPages(z *Editor) int {
+	n := 0
+	for i := 0; i < len(z.Rows); i += bufferPageSize {
+		if z.Rows[i] != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCustomBufferLoadsPagesOnDemand(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	buf := newCountingBuffer(100 * bufferPageSize)
+	z.Do(func() {
+		z.Config.MinRefreshInterval = 0
+		z.SetBuffer(buf)
+	})
+	z.Do(func() {
+		if got, want := z.LastLine(), buf.n-1; got != want {
+			t.Errorf("LastLine() = %d, want %d", got, want)
+		}
+		// the first page is displayed, and the last line is read once for LastPos
+		if len(buf.reads) != bufferPageSize+1 {
+			t.Errorf("%d lines read after displaying the first page, want %d", len(buf.reads), bufferPageSize+1)
+		}
+		line := 50*bufferPageSize + 3
+		if got, want := z.GetLineText(line), fmt.Sprintf("line %d ", line); got != want {
+			t.Errorf("GetLineText(%d) = %q, want %q", line, got, want)
+		}
+		if r, ok := z.CharAt(CharPos{Line: line, Column: 0}); !ok || r != 'l' {
+			t.Errorf("CharAt(%d:0) = %q, %v, want 'l', true", line, r, ok)
+		}
+		if len(buf.reads) != 2*bufferPageSize+1 {
+			t.Errorf("%d lines read after accessing a second page, want %d", len(buf.reads), 2*bufferPageSize+1)
+		}
+		for i := 0; i < buf.n; i += bufferPageSize {
+			z.GetLineText(i)
+		}
+		if n := loadedPages(z); n > maxLoadedPages {
+			t.Errorf("%d pages loaded after reading all lines, want at most %d", n, maxLoadedPages)
+		}
+		if z.Rows[0] == nil {
+			t.Error("the displayed page was evicted")
+		}
+	})
+}
+
+func TestCustomBufferMaterializesOnEdit(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	buf := newCountingBuffer(3 * bufferPageSize)
+	line := 2*bufferPageSize + 1
+	z.Do(func() {
+		z.SetBuffer(buf)
+		z.Insert([]rune("x"), CharPos{Line: line, Column: 0})
+	})
+	z.Do(func() {
+		if z.paged != nil {
+			t.Fatal("the custom buffer is still paged after an edit")
+		}
+		if n := loadedPages(z); n != 3 {
+			t.Errorf("%d pages loaded after an edit, want 3", n)
+		}
+		if got, want := z.GetLineText(line), fmt.Sprintf("xline %d ", line); got != want {
+			t.Errorf("GetLineText(%d) = %q, want %q", line, got, want)
+		}
+		if got, want := z.LastLine(), buf.n-1; got != want {
+			t.Errorf("LastLine() = %d, want %d", got, want)
+		}
+	})
+}
+
+func TestDocumentSetKeepsCustomBuffer(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	buf := newCountingBuffer(10 * bufferPageSize)
+	d := NewDocumentSet(z, "big")
+	z.Do(func() {
+		z.SetBuffer(buf)
+		d.OpenDocument("small")
+		z.SetText("abc")
+		d.OpenDocument("big")
+	})
+	z.Do(func() {
+		if z.paged == nil {
+			t.Fatal("the custom buffer was not restored as paged")
+		}
+		line := 9*bufferPageSize + 7
+		if got, want := z.GetLineText(line), fmt.Sprintf("line %d ", line); got != want {
+			t.Errorf("GetLineText(%d) = %q, want %q", line, got, want)
+		}
+		d.OpenDocument("small")
+		if got := z.Text(); got != "abc\n" {
+			t.Errorf("Text() = %q after switching back, want %q", got, "abc\n")
+		}
+	})
+}
+
+func TestSetBufferReportsInsertedRunes(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	buf := newCountingBuffer(3 * bufferPageSize)
+	z.Do(func() {
+		z.SetText("abc")
+		z.SetBuffer(buf)
+		change := z.LastChange()
+		if want := buf.RuneCount() + buf.n; change.Inserted != want {
+			t.Errorf("Inserted = %d, want %d", change.Inserted, want)
+		}
+		if change.Removed != 4 {
+			t.Errorf("Removed = %d, want 4", change.Removed)
+		}
+		if want := (CharPos{Line: buf.n - 1, Column: len(fmt.Sprintf("line %d", buf.n-1))}); change.Interval.End != want {
+			t.Errorf("Interval.End = %v, want %v", change.Interval.End, want)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package zedit
+
+import "sort"
+
+// AddCaret adds a secondary caret at the given position. Secondary carets are drawn like the caret
+// and receive typed runes and Backspace along with it. Their positions are kept up to date when the
+// text is edited. Nothing is done if there is already a caret at the position.
+func (z *Editor) AddCaret(pos CharPos) {
+	pos = MinPos(CharPos{Line: max(pos.Line, 0), Column: max(pos.Column, 0)}, z.LastPos())
+	for _, p := range z.Carets() {
+		if p == pos {
+			return
+		}
+	}
+	z.carets = append(z.carets, z.CreateAnchor(pos))
+	z.Refresh()
+}
+
+// ClearCarets removes all secondary carets.
+func (z *Editor) ClearCarets() {
+	if len(z.carets) == 0 {
+		return
+	}
+	for _, a := range z.carets {
+		a.Release()
+	}
+	z.carets = nil
+	z.Refresh()
+}
+
+// Carets returns the position of the caret followed by the positions of the secondary carets in
+// the order in which they were added.
+func (z *Editor) Carets() []CharPos {
+	result := make([]CharPos, 0, len(z.carets)+1)
+	result = append(result, z.caretPos)
+	for _, a := range z.carets {
+		if pos, ok := a.Pos(); ok {
+			result = append(result, pos)
+		}
+	}
+	return result
+}
+
+// HasMultipleCarets returns true if there are secondary carets.
+func (z *Editor) HasMultipleCarets() bool {
+	return len(z.carets) > 0
+}
+
+// editAtCarets calls edit for the caret and all secondary carets, from the last position in the text
+// to the first, so that an edit does not affect the positions at which the next edits take place.
+// The edit function returns the new position of the caret it was called for. Carets that end up
+// at the same position are merged.
+func (z *Editor) editAtCarets(edit func(pos CharPos) CharPos) {
+	primary := z.CreateAnchor(z.caretPos)
+	defer primary.Release()
+	anchors := append([]*Anchor{primary}, z.carets...)
+	sort.SliceStable(anchors, func(i, j int) bool {
+		a, _ := anchors[i].Pos()
+		b, _ := anchors[j].Pos()
+		return CmpPos(a, b) > 0
+	})
+	for _, a := range anchors {
+		pos, ok := a.Pos()
+		if !ok {
+			continue
+		}
+		a.setPos(edit(pos))
+	}
+	if pos, ok := primary.Pos(); ok {
+		z.SetCaret(pos)
+	}
+	seen := map[CharPos]bool{z.caretPos: true}
+	carets := z.carets[:0]
+	for _, a := range z.carets {
+		pos, ok := a.Pos()
+		if !ok || seen[pos] {
+			a.Release()
+			continue
+		}
+		seen[pos] = true
+		carets = append(carets, a)
+	}
+	z.carets = carets
+	z.Refresh()
+}
+
+// typeAtCarets inserts the runes at the caret and all secondary carets and puts each caret after the
+// inserted runes. It returns false if there are no secondary carets.
+func (z *Editor) typeAtCarets(r []rune) bool {
+	if len(z.carets) == 0 {
+		return false
+	}
+	z.RemoveSelection()
+	z.fillVirtualSpace()
+	z.editAtCarets(func(pos CharPos) CharPos {
+		start, composed := z.composeInput(r, pos)
+		z.Insert(r, pos)
+		return z.advancePos(start, len(composed))
+	})
+	return true
+}
+
+// backspaceAtCarets deletes the rune left of the caret and of all secondary carets. It returns false
+// if there are no secondary carets.
+func (z *Editor) backspaceAtCarets() bool {
+	if len(z.carets) == 0 {
+		return false
+	}
+	z.RemoveSelection()
+	z.editAtCarets(func(pos CharPos) CharPos {
+		prev, ok := z.PrevPos(pos)
+		if !ok {
+			return pos
+		}
+		z.Delete(CharInterval{Start: prev, End: prev})
+		return prev
+	})
+	return true
+}
@@ -0,0 +1,88 @@
+package zedit
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// UpperCaseSelection converts the selected text to upper case, or the word under the caret if there is
+// no selection. Tags and the selection are preserved.
+func (z *Editor) UpperCaseSelection() {
+	z.transformCase(func(r rune, _ bool) rune { return unicode.ToUpper(r) })
+}
+
+// LowerCaseSelection converts the selected text to lower case, or the word under the caret if there is
+// no selection. Tags and the selection are preserved.
+func (z *Editor) LowerCaseSelection() {
+	z.transformCase(func(r rune, _ bool) rune { return unicode.ToLower(r) })
+}
+
+// TitleCaseSelection converts the first letter of each word in the selected text to title case and
+// the other letters to lower case, or does so for the word under the caret if there is no selection.
+// Words are delimited by runes for which IsWordRune returns false. Tags and the selection are preserved.
+func (z *Editor) TitleCaseSelection() {
+	z.transformCase(func(r rune, wordStart bool) rune {
+		if wordStart {
+			return unicode.ToTitle(r)
+		}
+		return unicode.ToLower(r)
+	})
+}
+
+// transformCase replaces each rune in the selection or the word under the caret by the result of fn,
+// which is passed the rune and whether it starts a word. Since the line structure does not change, the
+// text is not reflown. Runes whose case mapping would be another rune are changed in place.
+func (z *Editor) transformCase(fn func(r rune, wordStart bool) rune) {
+	if z.Config.ReadOnly || z.hex != nil {
+		return
+	}
+	interval, ok := z.CurrentSelection()
+	if !ok {
+		var word string
+		word, interval = z.getWordAt(z.caretPos)
+		if word == "" {
+			return
+		}
+	}
+	interval = interval.Sanitize(z.LastPos())
+	if z.isProtectedRange(interval) {
+		return
+	}
+	z.materialize()
+	wordStart := true
+	if prev, ok := z.PrevPos(interval.Start); ok && prev.Column < z.LastColumn(prev.Line) {
+		wordStart = !IsWordRune(z.Rows[prev.Line][prev.Column])
+	}
+	changed := false
+	for pos := interval.Start; CmpPos(pos, interval.End) <= 0; {
+		if pos.Column >= z.LastColumn(pos.Line) {
+			// line endings are kept, but a hard line feed ends a word
+			if z.Rows[pos.Line][pos.Column] != z.Config.SoftLF {
+				wordStart = true
+			}
+		} else {
+			r := z.Rows[pos.Line][pos.Column]
+			isWord := IsWordRune(r)
+			if c := fn(r, wordStart && isWord); c != r {
+				z.Rows[pos.Line][pos.Column] = c
+				changed = true
+			}
+			wordStart = !isWord
+		}
+		next, ok := z.NextPos(pos)
+		if !ok {
+			break
+		}
+		pos = next
+	}
+	if !changed {
+		return
+	}
+	for row := z.FindParagraphStart(interval.Start.Line, z.Config.HardLF); row <= interval.End.Line; row = z.FindParagraphEnd(row, z.Config.HardLF) + 1 {
+		z.highlightEdit(row)
+	}
+	z.markChanged()
+	n := utf8.RuneCountInString(z.GetTextRange(interval))
+	z.notifyChange(Change{Interval: interval, Inserted: n, Removed: n})
+	z.Refresh()
+}
@@ -0,0 +1,138 @@
+package zedit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// runConcurrently runs the given functions in separate goroutines and fails the test if they do not
+// all return within a generous timeout, which indicates a deadlock.
+func runConcurrently(t *testing.T, fns ...func()) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("goroutines did not finish, the editor is probably deadlocked")
+	}
+}
+
+func TestConcurrentPrintAndScroll(t *testing.T) {
+	z := newTestEditor(t, 40, 10)
+	z.Do(func() { z.SetText(strings.Repeat("some text\n", 50)) })
+	runConcurrently(t,
+		func() {
+			for i := range 200 {
+				z.Do(func() { z.Print(fmt.Sprintf("printed line %d\n", i), nil) })
+			}
+		},
+		func() {
+			for i := range 200 {
+				z.Do(func() { z.SetTopLine(min(i, z.maxLineOffset())) })
+				z.Scrolled(&fyne.ScrollEvent{Scrolled: fyne.Delta{DY: float32(i%7-3) * 50}})
+			}
+		},
+		func() {
+			for i := range 200 {
+				// what Fyne does when the scroll bar is dragged
+				z.scroll.OnScrolled(fyne.Position{Y: float32(i%50) * z.RowHeight()})
+				z.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDown})
+			}
+		},
+	)
+}
+
+// TestPrintSyncWhileRefreshing is intended to be run with the race detector, which reports accesses
+// to the text by Print_Sync and RuneAt_Sync that are not synchronized with refreshes and caret
+// blinking.
+func TestPrintSyncWhileRefreshing(t *testing.T) {
+	z := newTestEditor(t, 40, 10)
+	z.Do(func() {
+		z.Config.ConsoleMode = true
+		z.Config.MaxPrintLines = 100
+		z.BlinkCaret(true)
+	})
+	runConcurrently(t,
+		func() {
+			for i := range 300 {
+				if i%2 == 0 {
+					z.Print_Sync(fmt.Sprintf("printed %d ", i), nil)
+				} else {
+					z.PrintLine_Sync(fmt.Sprintf("line %d", i), nil)
+				}
+			}
+		},
+		func() {
+			for range 300 {
+				z.Do(z.Refresh)
+			}
+		},
+		func() {
+			for range 100 {
+				z.Do(z.Invalidate)
+				time.Sleep(time.Millisecond)
+			}
+		},
+		func() {
+			for i := range 300 {
+				z.RuneAt_Sync(i%100, 0)
+			}
+		},
+	)
+	z.Do(func() {
+		if n := len(z.Rows); n > 100 {
+			t.Errorf("%d rows after printing, want at most 100", n)
+		}
+		if got := z.Text(); !strings.Contains(got, "line 299") {
+			t.Errorf("the last printed line is missing from the text %q", got)
+		}
+	})
+}
+
+// TestConcurrentAutoSize is intended to be run with the race detector, which reports rewrapping on
+// resize that is not synchronized with input.
+func TestConcurrentAutoSize(t *testing.T) {
+	z := newTestEditor(t, 40, 10)
+	z.Do(func() {
+		z.Config.AutoSize = true
+		z.SetText(strings.Repeat("some text that is wrapped at the width of the editor ", 20))
+	})
+	r := z.CreateRenderer()
+	runConcurrently(t,
+		func() {
+			for i := range 100 {
+				r.Layout(fyne.NewSize(float32(300+i%5*60), float32(200+i%3*40)))
+			}
+		},
+		func() {
+			for range 100 {
+				z.TypedRune('x')
+				z.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDown})
+			}
+		},
+	)
+	size := fyne.NewSize(400, 300)
+	r.Layout(size)
+	z.Do(func() {
+		if columns, lines := z.sizeToViewport(size); z.Columns != columns+1 || z.Lines != lines {
+			t.Errorf("viewport is %d columns and %d lines, want %d and %d", z.Columns, z.Lines, columns+1, lines)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package zedit
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SetContextMenu sets the menu shown when the editor is right-clicked. If menu is nil, a default
+// menu with Cut, Copy, Paste, and Select All is shown. Actions of a custom menu are called outside
+// the edit lock, so they should modify the editor by calling Do.
+func (z *Editor) SetContextMenu(menu *fyne.Menu) {
+	z.contextMenu = menu
+}
+
+// TappedSecondary implements fyne.SecondaryTappable. It puts the caret at the clicked position and
+// shows the context menu. A selection is kept if the click is within it and removed otherwise.
+func (z *Editor) TappedSecondary(evt *fyne.PointEvent) {
+	z.editMutex.Lock()
+	pos := z.PosToCharPos(evt.Position)
+	if !pos.IsLineNumber {
+		if sel, ok := z.CurrentSelection(); !ok || !sel.Contains(pos) {
+			z.RemoveSelection()
+		}
+		z.SetCaret(pos)
+	}
+	z.Focus()
+	menu := z.contextMenu
+	if menu == nil {
+		menu = z.defaultContextMenu()
+	}
+	z.editMutex.Unlock()
+	app := fyne.CurrentApp()
+	if app == nil {
+		return
+	}
+	if c := app.Driver().CanvasForObject(z); c != nil {
+		widget.ShowPopUpMenuAtPosition(menu, c, evt.AbsolutePosition)
+	}
+}
+
+// defaultContextMenu returns a menu with Cut, Copy, Paste, and Select All, which are disabled if there
+// is no selection or the editor is read-only.
+func (z *Editor) defaultContextMenu() *fyne.Menu {
+	_, hasSelection := z.CurrentSelection()
+	cut := fyne.NewMenuItem("Cut", func() { z.Do(z.Cut) })
+	cut.Disabled = !hasSelection || z.Config.ReadOnly
+	cpy := fyne.NewMenuItem("Copy", func() { z.Do(z.Copy) })
+	cpy.Disabled = !hasSelection
+	paste := fyne.NewMenuItem("Paste", func() { z.Do(z.Paste) })
+	paste.Disabled = z.Config.ReadOnly
+	selectAll := fyne.NewMenuItem("Select All", func() { z.Do(z.SelectAll) })
+	return fyne.NewMenu("", cut, cpy, paste, fyne.NewMenuItemSeparator(), selectAll)
+}
@@ -0,0 +1,132 @@
+package zedit
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2/theme"
+	"golang.org/x/exp/slices"
+)
+
+// shownRow describes what a grid row displayed at the last refresh. A row needs to be rendered and
+// styled again only if its text line, the text of that line, or the tags intersecting it have changed.
+type shownRow struct {
+	valid   bool
+	line    int
+	virtual *virtualLine
+	text    []rune
+	tags    []TagWithInterval
+}
+
+// displaySettings are the settings that affect all displayed rows. All rows are rendered again if
+// any of them changes.
+type displaySettings struct {
+	lines, columns   int
+	columnOffset     int
+	trailingWS       bool
+	controlChars     ControlCharPolicy
+	endOfBuffer      rune
+	placeHolder      bool
+	lineNumberStyle  Style
+	stylerGeneration uint64
+}
+
+// Invalidate marks all displayed rows as changed, so that they are rendered and styled again at the
+// next refresh. Rows are otherwise only redrawn if their text or the tags intersecting them have
+// changed, so Invalidate must be called if a tag styler or rule styler changes the way it styles
+// text without a change of the text or tags, e.g. because it depends on external data.
+func (z *Editor) Invalidate() {
+	z.shownRows = nil
+	z.Refresh()
+}
+
+// staleRows returns the indices of the grid rows that need to be rendered and styled because they
+// display something else than at the last refresh or the caret was drawn into them, and remembers
+// what the rows display now. The display lines must have been computed.
+func (z *Editor) staleRows() []int {
+	colors := [2]color.Color{theme.TextColor(), theme.BackgroundColor()}
+	settings := displaySettings{lines: z.Lines, columns: z.Columns, columnOffset: z.columnOffset,
+		trailingWS: z.Config.HighlightTrailingWhitespace, controlChars: z.Config.ControlCharPolicy,
+		endOfBuffer: z.Config.EndOfBufferChar, placeHolder: z.showsPlaceHolder(),
+		lineNumberStyle: z.lineNumberStyle, stylerGeneration: z.Styles.generation()}
+	if !sameColor(colors[0], z.shownColors[0]) || !sameColor(colors[1], z.shownColors[1]) ||
+		settings != z.shownSettings || len(z.shownRows) != z.Lines {
+		z.shownRows = make([]shownRow, z.Lines)
+		z.shownLineNumbers = make([]string, z.Lines)
+		z.shownColors = colors
+		z.shownSettings = settings
+	}
+	stale := make([]int, 0, z.Lines)
+	for i := range z.Lines {
+		if !z.updateShownRow(i) || slices.Contains(z.caretGridRows, i) {
+			stale = append(stale, i)
+		}
+	}
+	z.caretGridRows = z.caretGridRows[:0]
+	return stale
+}
+
+// updateShownRow compares what grid row i displays now with what it displayed at the last refresh
+// and returns true if it is unchanged. Otherwise, the row is remembered as displayed now.
+func (z *Editor) updateShownRow(i int) bool {
+	line, virtual := z.displayLines[i], z.displayVirtual[i]
+	var text []rune
+	var tags []TagWithInterval
+	if virtual == nil && line >= 0 && line < len(z.Rows) {
+		text = z.row(line)
+		tags = z.lineTags(line)
+	}
+	shown := &z.shownRows[i]
+	if shown.valid && shown.line == line && shown.virtual == virtual && slices.Equal(shown.text, text) &&
+		slices.Equal(shown.tags, tags) {
+		return true
+	}
+	*shown = shownRow{valid: true, line: line, virtual: virtual, text: append(shown.text[:0], text...), tags: tags}
+	return false
+}
+
+// lineTags returns the tags intersecting the given text line together with their intervals, sorted
+// by their intervals.
+func (z *Editor) lineTags(line int) []TagWithInterval {
+	tags, ok := z.Tags.LookupRange(z.linesInterval(line, 1))
+	if !ok {
+		return nil
+	}
+	result := make([]TagWithInterval, 0, len(tags))
+	for _, tag := range tags {
+		if tag == nil {
+			continue
+		}
+		if iv, ok := z.Tags.Lookup(tag); ok {
+			result = append(result, TagWithInterval{Tag: tag, Interval: iv})
+		}
+	}
+	slices.SortStableFunc(result, func(a, b TagWithInterval) int {
+		if c := CmpPos(a.Interval.Start, b.Interval.Start); c != 0 {
+			return c
+		}
+		return CmpPos(a.Interval.End, b.Interval.End)
+	})
+	return result
+}
+
+// maskLines returns a copy of the display lines in which the lines of the grid rows not in rows are
+// replaced by -1, so that only the given rows are styled.
+func (z *Editor) maskLines(rows []int) []int {
+	lines := make([]int, len(z.displayLines))
+	for i := range lines {
+		lines[i] = -1
+	}
+	for _, i := range rows {
+		lines[i] = z.displayLines[i]
+	}
+	return lines
+}
+
+// refreshGridRows refreshes the row grids displaying the given rows of the internal grid.
+func (z *Editor) refreshGridRows(rows []int) {
+	for _, i := range rows {
+		if i >= 0 && i < len(z.rowGrids.Objects) {
+			z.rowGrids.Objects[i].Refresh()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package zedit
+
+import (
+	"crypto/sha256"
+)
+
+// document holds the text and view state of a document that is not currently shown in the editor.
+type document struct {
+	rows         [][]rune
+	paged        *pagedBuffer
+	anchors      map[*Anchor]struct{}
+	tags         []TagWithInterval
+	virtualLines []*virtualLine
+	caretPos     CharPos
+	lineOffset   int
+	columnOffset int
+	modified     bool
+	fileHash     [sha256.Size]byte
+	highlight    []HighlightState
+}
+
+// DocumentSet manages multiple documents that are displayed one at a time in the same editor, which
+// can be used for implementing a tabbed editor. Each document retains its text, tags, caret, scroll
+// position, and modified state while another document is shown. Documents are identified by
+// arbitrary ids chosen by the caller.
+type DocumentSet struct {
+	editor  *Editor
+	docs    map[string]*document
+	order   []string
+	current string
+}
+
+// NewDocumentSet returns a new document set for the given editor. The text currently in the editor
+// becomes the document with the given id.
+func NewDocumentSet(z *Editor, id string) *DocumentSet {
+	d := DocumentSet{editor: z, docs: make(map[string]*document), current: id}
+	d.docs[id] = nil
+	d.order = append(d.order, id)
+	return &d
+}
+
+// CurrentDocument returns the id of the document currently shown in the editor.
+func (d *DocumentSet) CurrentDocument() string {
+	return d.current
+}
+
+// Documents returns the ids of all documents in the order in which they were opened.
+func (d *DocumentSet) Documents() []string {
+	result := make([]string, len(d.order))
+	copy(result, d.order)
+	return result
+}
+
+// HasDocument returns true if there is a document with the given id.
+func (d *DocumentSet) HasDocument(id string) bool {
+	_, ok := d.docs[id]
+	return ok
+}
+
+// OpenDocument shows the document with the given id in the editor, storing the state of the current
+// document. If there is no document with this id, a new empty document is created.
+func (d *DocumentSet) OpenDocument(id string) {
+	if id == d.current {
+		return
+	}
+	d.docs[d.current] = d.store()
+	doc, ok := d.docs[id]
+	d.current = id
+	if !ok || doc == nil {
+		if !ok {
+			d.order = append(d.order, id)
+		}
+		d.clear()
+		return
+	}
+	d.docs[id] = nil
+	d.restore(doc)
+}
+
+// CloseDocument removes the document with the given id. If it is the current document, the
+// previously opened document is shown instead, or a new empty document with the same id if it was
+// the only one.
+func (d *DocumentSet) CloseDocument(id string) {
+	idx := -1
+	for i := range d.order {
+		if d.order[i] == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+	if id != d.current {
+		delete(d.docs, id)
+		d.order = append(d.order[:idx], d.order[idx+1:]...)
+		return
+	}
+	if len(d.order) == 1 {
+		d.clear()
+		return
+	}
+	next := d.order[max(0, idx-1)]
+	if idx == 0 {
+		next = d.order[1]
+	}
+	d.OpenDocument(next)
+	delete(d.docs, id)
+	d.order = append(d.order[:idx], d.order[idx+1:]...)
+}
+
+// clear shows a new empty document in the editor.
+func (d *DocumentSet) clear() {
+	z := d.editor
+	z.virtualLines = nil
+	z.caretPos = CharPos{}
+	z.columnOffset = 0
+	z.SetText("")
+	z.fileHash = [sha256.Size]byte{}
+	z.modified = false
+	z.SetTopLine(0)
+	z.SetCaret(CharPos{})
+}
+
+// store returns the state of the document currently shown in the editor.
+func (d *DocumentSet) store() *document {
+	z := d.editor
+	return &document{
+		rows:         z.Rows,
+		paged:        z.paged,
+		anchors:      z.anchors,
+		tags:         z.Tags.AllTags(),
+		virtualLines: z.virtualLines,
+		caretPos:     z.caretPos,
+		lineOffset:   z.lineOffset,
+		columnOffset: z.columnOffset,
+		modified:     z.modified,
+		fileHash:     z.fileHash,
+		highlight:    z.highlightStates,
+	}
+}
+
+// restore shows the given document in the editor.
+func (d *DocumentSet) restore(doc *document) {
+	z := d.editor
+	z.Rows = doc.rows
+	z.paged = doc.paged
+	z.anchors = doc.anchors
+	z.Tags.SetAllTags(doc.tags)
+	z.virtualLines = doc.virtualLines
+	z.modified = doc.modified
+	z.fileHash = doc.fileHash
+	z.highlightStates = doc.highlight
+	z.maxLineLenValid = false
+	z.columnOffset = doc.columnOffset
+	z.SetTopLine(doc.lineOffset)
+	z.SetCaret(doc.caretPos)
+}
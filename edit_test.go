@@ -0,0 +1,228 @@
+package zedit
+
+import (
+	"regexp"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestDeclinedBulkEditAbortsInput(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Config.BulkEditThreshold = 1
+	z.Config.OnBulkEdit = func(interval CharInterval, n int) bool { return false }
+	const text = "hello world"
+	inputs := []struct {
+		name  string
+		input func()
+	}{
+		{"rune", func() { z.TypedRune('x') }},
+		{"return", func() { z.Do(z.Return) }},
+		{"tab", func() { z.Do(z.Tab) }},
+	}
+	for _, tt := range inputs {
+		t.Run(tt.name, func(t *testing.T) {
+			var before string
+			z.Do(func() {
+				z.SetText(text)
+				before = z.Text()
+				z.Select(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 0, Column: 4}})
+				z.SetCaret(CharPos{Line: 0, Column: 5})
+			})
+			tt.input()
+			var got string
+			var caret CharPos
+			z.Do(func() {
+				got = z.Text()
+				caret = z.caretPos
+			})
+			if got != before {
+				t.Errorf("text = %q, want %q", got, before)
+			}
+			if caret != (CharPos{Line: 0, Column: 5}) {
+				t.Errorf("caret = %v, want 0:5", caret)
+			}
+		})
+	}
+}
+
+func TestDeleteAtTextBoundaries(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		caret  func(z *Editor) CharPos
+		delete func(z *Editor)
+	}{
+		{"Delete1 at last position", "ab\ncd", (*Editor).LastPos, (*Editor).Delete1},
+		{"Delete1 at last position of empty text", "", (*Editor).LastPos, (*Editor).Delete1},
+		{"Backspace at start", "ab\ncd", func(z *Editor) CharPos { return CharPos{} }, (*Editor).Backspace},
+		{"Backspace at start of empty text", "", func(z *Editor) CharPos { return CharPos{} }, (*Editor).Backspace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newTestEditor(t, 80, 10)
+			var before, after string
+			var caret, want CharPos
+			z.Do(func() {
+				z.SetText(tt.text)
+				before = z.Text()
+				want = tt.caret(z)
+				z.SetCaret(want)
+				tt.delete(z)
+				after = z.Text()
+				caret = z.caretPos
+			})
+			if after != before {
+				t.Errorf("text = %q, want %q", after, before)
+			}
+			if caret != want {
+				t.Errorf("caret = %v, want %v", caret, want)
+			}
+		})
+	}
+}
+
+func TestVirtualSpaceIsFilledBeforeInput(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	w := test.NewTempWindow(t, z)
+	z.Config.VirtualSpace = true
+	w.Clipboard().SetContent("x")
+	inputs := []struct {
+		name  string
+		input func()
+		want  string
+	}{
+		{"rune at carets", func() {
+			z.Do(func() { z.AddCaret(CharPos{Line: 1, Column: 0}) })
+			z.TypedRune('x')
+		}, "ab  x\nxcd\n"},
+		{"return", func() { z.Do(z.Return) }, "ab  \n\ncd\n"},
+		{"paste", func() { z.Do(z.Paste) }, "ab  x\ncd\n"},
+	}
+	for _, tt := range inputs {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			z.Do(func() {
+				z.SetText("ab\ncd")
+				z.SetCaret(CharPos{Line: 0, Column: 2})
+				z.MoveCaret(CaretRight)
+				z.MoveCaret(CaretRight)
+			})
+			tt.input()
+			z.Do(func() {
+				got = z.Text()
+				z.ClearCarets()
+			})
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaretDownKeepsVirtualSpaceOutOfWrappedRows(t *testing.T) {
+	z := newTestEditor(t, 10, 10)
+	z.Do(func() {
+		z.Config.VirtualSpace = true
+		z.SetText("abcdefgh\nabc def ghi jkl")
+		z.SetCaret(CharPos{Line: 0, Column: 8})
+		for range 4 {
+			z.MoveCaret(CaretRight)
+		}
+		z.MoveCaret(CaretDown)
+		if z.row(z.caretPos.Line)[len(z.row(z.caretPos.Line))-1] != z.Config.SoftLF {
+			t.Fatalf("row %d is not soft-wrapped", z.caretPos.Line)
+		}
+		if z.virtualSpace != 0 {
+			t.Errorf("virtual space = %d on a soft-wrapped row, want 0", z.virtualSpace)
+		}
+	})
+}
+
+func TestProtectedSelectionIsNotReplaced(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	w := test.NewTempWindow(t, z)
+	z.Config.ProtectedTag = NewTag("protected")
+	w.Clipboard().SetContent("x")
+	const text = "abc def ghi"
+	inputs := []struct {
+		name  string
+		input func()
+	}{
+		{"rune", func() { z.TypedRune('x') }},
+		{"return", func() { z.Do(z.Return) }},
+		{"paste", func() { z.Do(z.Paste) }},
+	}
+	for _, tt := range inputs {
+		t.Run(tt.name, func(t *testing.T) {
+			var before string
+			z.Do(func() {
+				z.SetText(text)
+				before = z.Text()
+				z.Tags.Add(CharInterval{Start: CharPos{Line: 0, Column: 4}, End: CharPos{Line: 0, Column: 6}},
+					z.Tags.CloneTag(z.Config.ProtectedTag))
+				z.Select(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 0, Column: 5}})
+			})
+			tt.input()
+			var got string
+			z.Do(func() { got = z.Text() })
+			if got != before {
+				t.Errorf("text = %q, want %q", got, before)
+			}
+		})
+	}
+	t.Run("replace all", func(t *testing.T) {
+		var n int
+		var got string
+		z.Do(func() {
+			z.SetText("ab ab ab")
+			z.Tags.Add(CharInterval{Start: CharPos{Line: 0, Column: 3}, End: CharPos{Line: 0, Column: 4}},
+				z.Tags.CloneTag(z.Config.ProtectedTag))
+			n = z.ReplaceAll(regexp.MustCompile("ab"), "x")
+			got = z.Text()
+		})
+		if want := "x ab x\n"; got != want || n != 2 {
+			t.Errorf("ReplaceAll = %d, text %q, want 2, %q", n, got, want)
+		}
+	})
+}
+
+func TestTypedCombiningMarkIsComposed(t *testing.T) {
+	tests := []struct {
+		name  string
+		form  NormalizationForm
+		input []rune
+		want  string
+		caret int
+	}{
+		{"NFC combining mark", NormalizeNFC, []rune{'\u0301', 'x'}, "caf\u00e9x\n", 5},
+		{"NFC precomposed", NormalizeNFC, []rune{'\u00e9'}, "cafe\u00e9\n", 5},
+		{"NFD precomposed", NormalizeNFD, []rune{'\u00e9'}, "cafee\u0301\n", 6},
+		{"none", NormalizeNone, []rune{'\u0301'}, "cafe\u0301\n", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newTestEditor(t, 80, 10)
+			z.Config.NormalizeForm = tt.form
+			z.Do(func() {
+				z.SetText("cafe")
+				z.SetCaret(CharPos{Line: 0, Column: 4})
+			})
+			for _, r := range tt.input {
+				z.TypedRune(r)
+			}
+			var got string
+			var caret CharPos
+			z.Do(func() {
+				got = z.Text()
+				caret = z.caretPos
+			})
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if caret != (CharPos{Line: 0, Column: tt.caret}) {
+				t.Errorf("caret = %v, want 0:%d", caret, tt.caret)
+			}
+		})
+	}
+}
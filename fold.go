@@ -0,0 +1,172 @@
+package zedit
+
+import (
+	"strings"
+)
+
+// foldRegion is a range of lines that can be folded, so that only its first line is displayed. Its
+// lines are tracked by a tag so that the region moves with the text when it is edited.
+type foldRegion struct {
+	tag    Tag
+	folded bool
+}
+
+// AddFoldRegion adds a foldable region from startLine to endLine. The region is not folded initially.
+// Nothing is done if the region would not contain at least two lines.
+func (z *Editor) AddFoldRegion(startLine, endLine int) {
+	if startLine < 0 || endLine > z.LastLine() || endLine <= startLine {
+		return
+	}
+	tag := z.Tags.CloneTag(NewTag("_fold"))
+	z.Tags.Upsert(tag, CharInterval{Start: CharPos{Line: startLine, Column: 0},
+		End: CharPos{Line: endLine, Column: z.LastColumn(endLine)}})
+	z.folds = append(z.folds, &foldRegion{tag: tag})
+}
+
+// FoldRegions scans the text for lines containing startMarker and endMarker and adds a foldable
+// region from each start marker line to the matching end marker line. Regions may be nested. The
+// number of regions added is returned. For example, FoldRegions("// region", "// endregion") makes
+// the code between such comments foldable.
+func (z *Editor) FoldRegions(startMarker, endMarker string) int {
+	var stack []int
+	n := 0
+	for i := range z.Rows {
+		s := string(z.row(i))
+		switch {
+		case strings.Contains(s, endMarker) && len(stack) > 0:
+			start := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if i > start {
+				z.AddFoldRegion(start, i)
+				n++
+			}
+		case strings.Contains(s, startMarker):
+			stack = append(stack, i)
+		}
+	}
+	return n
+}
+
+// Fold folds the innermost region starting at the given line and returns true, or returns false if
+// there is no region starting at the line.
+func (z *Editor) Fold(line int) bool {
+	return z.setFolded(line, true)
+}
+
+// Unfold unfolds the region starting at the given line and returns true, or returns false if there
+// is no region starting at the line.
+func (z *Editor) Unfold(line int) bool {
+	return z.setFolded(line, false)
+}
+
+// ToggleFold folds the region starting at the given line if it is unfolded and unfolds it otherwise.
+// It returns false if there is no region starting at the line.
+func (z *Editor) ToggleFold(line int) bool {
+	if region, _, ok := z.foldRegionAt(line); ok {
+		return z.setFolded(line, !region.folded)
+	}
+	return false
+}
+
+// UnfoldAll unfolds all regions.
+func (z *Editor) UnfoldAll() {
+	for _, region := range z.folds {
+		region.folded = false
+	}
+	z.Refresh()
+}
+
+// ClearFoldRegions removes all foldable regions, unfolding them.
+func (z *Editor) ClearFoldRegions() {
+	for _, region := range z.folds {
+		z.Tags.Delete(region.tag)
+	}
+	z.folds = nil
+	z.Refresh()
+}
+
+// IsFolded returns true if the given line is hidden in a folded region.
+func (z *Editor) IsFolded(line int) bool {
+	for _, region := range z.folds {
+		if !region.folded {
+			continue
+		}
+		if interval, ok := z.Tags.Lookup(region.tag); ok && line > interval.Start.Line && line <= interval.End.Line {
+			return true
+		}
+	}
+	return false
+}
+
+// setFolded sets the folding state of the region starting at line, moving the caret out of the
+// region if it is hidden by folding it.
+func (z *Editor) setFolded(line int, folded bool) bool {
+	region, interval, ok := z.foldRegionAt(line)
+	if !ok {
+		return false
+	}
+	region.folded = folded
+	if folded && z.caretPos.Line > interval.Start.Line && z.caretPos.Line <= interval.End.Line {
+		z.SetCaret(CharPos{Line: interval.Start.Line, Column: 0})
+	}
+	z.Refresh()
+	return true
+}
+
+// foldRegionAt returns the innermost region starting at the given line. Regions whose tag has been
+// removed because their text was deleted are removed.
+func (z *Editor) foldRegionAt(line int) (*foldRegion, CharInterval, bool) {
+	var found *foldRegion
+	var foundInterval CharInterval
+	for i := 0; i < len(z.folds); i++ {
+		interval, ok := z.Tags.Lookup(z.folds[i].tag)
+		if !ok {
+			z.folds = append(z.folds[:i], z.folds[i+1:]...)
+			i--
+			continue
+		}
+		if interval.Start.Line != line {
+			continue
+		}
+		if found == nil || interval.End.Line < foundInterval.End.Line {
+			found = z.folds[i]
+			foundInterval = interval
+		}
+	}
+	return found, foundInterval, found != nil
+}
+
+// foldedEnd returns the last line of the largest folded region starting at the given line, and false
+// if there is no folded region starting there.
+func (z *Editor) foldedEnd(line int) (int, bool) {
+	end, ok := line, false
+	for _, region := range z.folds {
+		if !region.folded {
+			continue
+		}
+		if interval, found := z.Tags.Lookup(region.tag); found && interval.Start.Line == line {
+			end = max(end, interval.End.Line)
+			ok = true
+		}
+	}
+	return end, ok
+}
+
+// visibleLine returns the given line if it is not hidden by folding, and otherwise the next visible
+// line after the folded region if down is true or the first line of the region if down is false.
+func (z *Editor) visibleLine(line int, down bool) int {
+	for _, region := range z.folds {
+		if !region.folded {
+			continue
+		}
+		interval, ok := z.Tags.Lookup(region.tag)
+		if !ok || line <= interval.Start.Line || line > interval.End.Line {
+			continue
+		}
+		if down && interval.End.Line < z.LastLine() {
+			return z.visibleLine(interval.End.Line+1, down)
+		}
+		return z.visibleLine(interval.Start.Line, false)
+	}
+	return line
+}
@@ -13,6 +13,7 @@ require (
 	github.com/phrozen/blend v0.0.0-20210220204729-f26b6cf7a28e
 	github.com/rdleal/intervalst v1.4.0
 	golang.org/x/exp v0.0.0-20240707233637-46b078467d37
+	golang.org/x/text v0.16.0
 )
 
 require (
@@ -45,7 +46,6 @@ require (
 	golang.org/x/mobile v0.0.0-20240707233753-b765e5d5218f // indirect
 	golang.org/x/net v0.27.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/text v0.16.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	honnef.co/go/js/dom v0.0.0-20231112215516-51f43a291193 // indirect
 )
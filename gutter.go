@@ -0,0 +1,120 @@
+package zedit
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"github.com/chewxy/math32"
+)
+
+// GutterMarker is an icon displayed in the line number gutter next to a line, e.g. to indicate a
+// breakpoint or an error. If Icon is nil, a dot in Color is displayed.
+type GutterMarker struct {
+	Icon  fyne.Resource // the icon, which takes precedence over Color
+	Color color.Color   // the color of the dot displayed if there is no icon
+}
+
+// SetGutterMarker displays the marker in the line number gutter next to the given line, replacing any
+// marker the line already has. The marker moves with the line when text is inserted or deleted above
+// it or a line break is inserted at its start. It is removed when the whole line is deleted. Gutter
+// markers are only visible if Config.ShowLineNumbers is true.
+func (z *Editor) SetGutterMarker(line int, marker GutterMarker) {
+	if line < 0 || line > z.LastLine() {
+		return
+	}
+	z.RemoveGutterMarker(line)
+	z.gutterMarkers[z.createLineAnchor(line)] = marker
+	z.Refresh()
+}
+
+// RemoveGutterMarker removes the marker of the given line, if there is one.
+func (z *Editor) RemoveGutterMarker(line int) {
+	for a := range z.gutterMarkers {
+		if pos, ok := a.Pos(); !ok || pos.Line == line {
+			a.Release()
+			delete(z.gutterMarkers, a)
+		}
+	}
+	z.Refresh()
+}
+
+// ClearGutterMarkers removes all gutter markers.
+func (z *Editor) ClearGutterMarkers() {
+	for a := range z.gutterMarkers {
+		a.Release()
+		delete(z.gutterMarkers, a)
+	}
+	z.Refresh()
+}
+
+// GutterMarkers returns the current gutter markers by line. Markers of deleted lines are removed.
+func (z *Editor) GutterMarkers() map[int]GutterMarker {
+	result := make(map[int]GutterMarker, len(z.gutterMarkers))
+	for a, marker := range z.gutterMarkers {
+		pos, ok := a.Pos()
+		if !ok {
+			a.Release()
+			delete(z.gutterMarkers, a)
+			continue
+		}
+		result[pos.Line] = marker
+	}
+	return result
+}
+
+// GutterClickLine returns the line that was clicked in the line number gutter. It is intended to be
+// called by GutterClickEvent handlers.
+func (z *Editor) GutterClickLine() int {
+	return z.gutterClickLine
+}
+
+// handleGutterClick records the clicked line and calls the handler for GutterClickEvent if there is one.
+func (z *Editor) handleGutterClick(line int) {
+	z.gutterClickLine = line
+	if handler, ok := z.eventHandlers[GutterClickEvent]; ok && handler != nil {
+		handler(GutterClickEvent, z)
+	}
+}
+
+// refreshGutterMarkers places the gutter marker objects next to the displayed lines that have markers.
+func (z *Editor) refreshGutterMarkers() {
+	markers := z.GutterMarkers()
+	objects := make([]fyne.CanvasObject, 0, len(markers))
+	if z.Config.ShowLineNumbers && len(markers) > 0 {
+		rowHeight := z.RowHeight()
+		size := min(math32.Round(z.charSize.Width), rowHeight)
+		origin := z.lineNumberGrid.Position()
+		for i := 0; i < z.Lines; i++ {
+			row := z.displayLines[i]
+			if row < 0 || z.displayVirtual[i] != nil {
+				continue
+			}
+			if i > 0 && z.displayLines[i-1] == row {
+				continue
+			}
+			marker, ok := markers[row]
+			if !ok {
+				continue
+			}
+			var obj fyne.CanvasObject
+			if marker.Icon != nil {
+				img := canvas.NewImageFromResource(marker.Icon)
+				img.FillMode = canvas.ImageFillContain
+				obj = img
+			} else {
+				fill := marker.Color
+				if fill == nil {
+					fill = theme.PrimaryColor()
+				}
+				obj = canvas.NewCircle(fill)
+			}
+			obj.Resize(fyne.Size{Width: size, Height: size})
+			obj.Move(origin.Add(fyne.Position{X: 0, Y: float32(i)*rowHeight + (rowHeight-size)/2}))
+			objects = append(objects, obj)
+		}
+	}
+	z.gutterOverlay.Objects = objects
+	z.gutterOverlay.Refresh()
+}
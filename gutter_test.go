@@ -0,0 +1,60 @@
+package zedit
+
+import (
+	"image/color"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// markedLines returns the sorted lines that have gutter markers.
+func markedLines(z *Editor) []int {
+	var lines []int
+	for line := range z.GutterMarkers() {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func TestGutterMarkerMovesWithLineBreakAtLineStart(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() {
+		z.SetText("aa\nbb\ncc")
+		z.SetGutterMarker(1, GutterMarker{Color: color.Black})
+		z.SetCaret(CharPos{Line: 1, Column: 0})
+		z.Return()
+		if got := markedLines(z); !slices.Equal(got, []int{2}) {
+			t.Errorf("marked lines %v after a line break at the start of the line, want [2]", got)
+		}
+		z.SetCaret(CharPos{Line: 0, Column: 1})
+		z.Return()
+		if got := markedLines(z); !slices.Equal(got, []int{3}) {
+			t.Errorf("marked lines %v after a line break above the line, want [3]", got)
+		}
+		z.Insert([]rune("x"), CharPos{Line: 3, Column: 0})
+		if got := markedLines(z); !slices.Equal(got, []int{3}) {
+			t.Errorf("marked lines %v after typing at the start of the line, want [3]", got)
+		}
+	})
+}
+
+func TestGutterMarkerRemovedWithWholeLine(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() {
+		z.SetText("aa\nbbb\ncc")
+		z.SetGutterMarker(1, GutterMarker{Color: color.Black})
+		z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 0}, End: CharPos{Line: 1, Column: 0}})
+		if got := markedLines(z); !slices.Equal(got, []int{1}) {
+			t.Errorf("marked lines %v after deleting the first char of the line, want [1]", got)
+		}
+		z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 0}, End: CharPos{Line: 1, Column: 1}})
+		if got := markedLines(z); !slices.Equal(got, []int{1}) {
+			t.Errorf("marked lines %v after deleting the text of the line, want [1]", got)
+		}
+		z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 0}, End: CharPos{Line: 1, Column: 0}})
+		if got := markedLines(z); len(got) != 0 {
+			t.Errorf("marked lines %v after deleting the whole line, want none", got)
+		}
+	})
+}
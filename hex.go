@@ -0,0 +1,121 @@
+package zedit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// layout of a hex dump line: 8 digits offset, 2 spaces, 16 hex bytes with a space each, a space, and
+// 16 ASCII chars between bars
+const (
+	hexBytesPerLine = 16
+	hexColumn       = 10
+	asciiColumn     = hexColumn + 3*hexBytesPerLine + 2
+)
+
+// hexState holds the editor state that is replaced while the editor is in hex mode.
+type hexState struct {
+	data     []byte
+	rows     [][]rune
+	paged    *pagedBuffer
+	anchors  map[*Anchor]struct{}
+	tags     []TagWithInterval
+	caretPos CharPos
+	top      int
+	lineWrap bool
+	modified bool
+	states   []HighlightState
+}
+
+// SetHexMode switches the editor into a read-only hex view of the UTF-8 bytes of the text or back to
+// the normal view. In hex mode, each line shows the byte offset, 16 bytes in hex and the same bytes as
+// ASCII chars. The text, tags, caret, and scroll position are restored when hex mode is switched off.
+// Use HexSelection to obtain the byte range of the current selection in hex mode.
+func (z *Editor) SetHexMode(on bool) {
+	if on == z.IsHexMode() {
+		return
+	}
+	if on {
+		state := &hexState{data: []byte(strings.TrimSuffix(z.Text(), "\n")), rows: z.Rows, paged: z.paged,
+			anchors: z.anchors, tags: z.Tags.AllTags(), caretPos: z.caretPos, top: z.lineOffset,
+			lineWrap: z.Config.LineWrap, modified: z.modified, states: z.highlightStates}
+		z.Config.LineWrap = false
+		z.hex = state
+		z.SetText(hexDump(state.data))
+		z.modified = state.modified
+		z.SetCaret(CharPos{})
+		z.SetTopLine(0)
+		return
+	}
+	state := z.hex
+	z.hex = nil
+	z.Config.LineWrap = state.lineWrap
+	z.Rows = state.rows
+	z.paged = state.paged
+	z.anchors = state.anchors
+	z.maxLineLenValid = false
+	z.Tags.SetAllTags(state.tags)
+	z.highlightStates = state.states
+	z.modified = state.modified
+	z.SetCaret(state.caretPos)
+	z.SetTopLine(SafePositiveValue(state.top, z.maxLineOffset()))
+}
+
+// IsHexMode returns true if the editor is in hex mode.
+func (z *Editor) IsHexMode() bool {
+	return z.hex != nil
+}
+
+// HexSelection returns the byte range from start to end (exclusive) of the current selection in hex
+// mode, where the selection may be made in the hex or the ASCII columns. It returns false if the
+// editor is not in hex mode or there is no selection.
+func (z *Editor) HexSelection() (int, int, bool) {
+	if z.hex == nil {
+		return 0, 0, false
+	}
+	sel, ok := z.CurrentSelection()
+	if !ok {
+		return 0, 0, false
+	}
+	start := z.hexOffsetAt(sel.Start)
+	end := min(z.hexOffsetAt(sel.End)+1, len(z.hex.data))
+	return start, max(start, end), true
+}
+
+// hexOffsetAt returns the byte offset displayed at the given position in hex mode.
+func (z *Editor) hexOffsetAt(pos CharPos) int {
+	var idx int
+	switch {
+	case pos.Column < hexColumn:
+		idx = 0
+	case pos.Column < asciiColumn-2:
+		idx = (pos.Column - hexColumn) / 3
+	case pos.Column < asciiColumn:
+		idx = hexBytesPerLine - 1
+	default:
+		idx = min(pos.Column-asciiColumn, hexBytesPerLine-1)
+	}
+	return min(pos.Line*hexBytesPerLine+idx, max(0, len(z.hex.data)-1))
+}
+
+// hexDump returns the hex mode display of the given data.
+func hexDump(data []byte) string {
+	var sb strings.Builder
+	for off := 0; off < len(data); off += hexBytesPerLine {
+		chunk := data[off:min(off+hexBytesPerLine, len(data))]
+		var hex, ascii strings.Builder
+		for _, b := range chunk {
+			fmt.Fprintf(&hex, "%02x ", b)
+			if b >= 0x20 && b < 0x7f {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		if off > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%08x  %-48s |%s|", off, hex.String(), ascii.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,352 @@
+package zedit
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2/theme"
+	"golang.org/x/exp/slices"
+)
+
+// TokenType is the category of a token returned by a Highlighter.
+type TokenType int
+
+const (
+	TokenText TokenType = iota // plain text, which is not styled
+	TokenKeyword
+	TokenString
+	TokenComment
+	TokenNumber
+	TokenOperator
+	TokenTypeName
+	TokenFunction
+)
+
+// tokenTypeNames are the names of the predefined token types.
+var tokenTypeNames = []string{"text", "keyword", "string", "comment", "number", "operator", "type", "function"}
+
+// String returns the name of the token type, e.g. "keyword".
+func (t TokenType) String() string {
+	if t >= 0 && int(t) < len(tokenTypeNames) {
+		return tokenTypeNames[t]
+	}
+	return strconv.Itoa(int(t))
+}
+
+// TagName returns the name of the tags with which tokens of this type are tagged. A TagStyler for
+// this name determines how tokens of the type are displayed.
+func (t TokenType) TagName() string {
+	return "_token_" + t.String()
+}
+
+// Token is a token of the given type found by a Highlighter, from the rune offset Start to End
+// (exclusive) in the tokenized paragraph.
+type Token struct {
+	Type       TokenType
+	Start, End int
+}
+
+// HighlightState is the lexer state of a Highlighter at the end of a paragraph, which is passed to
+// the Highlighter for tokenizing the next paragraph. It is nil before the first paragraph. States
+// are compared with ==, so they must be comparable values.
+type HighlightState any
+
+// Highlighter tokenizes the text for syntax highlighting. Tokenize is called with the text of a
+// paragraph without its line feed and the state at the end of the previous paragraph, and returns
+// the tokens in the paragraph and the state at its end. The state allows for multi-line constructs
+// such as block comments.
+type Highlighter interface {
+	Tokenize(line []rune, state HighlightState) ([]Token, HighlightState)
+}
+
+// TokenTheme maps token types to the styles with which tokens of these types are displayed. Colors
+// that are nil and style flags that are false leave the style of the text unchanged.
+type TokenTheme map[TokenType]Style
+
+// DefaultTokenTheme returns a token theme derived from the colors of the current Fyne theme.
+func DefaultTokenTheme() TokenTheme {
+	return TokenTheme{
+		TokenKeyword:  {FGColor: theme.PrimaryColor(), Bold: true},
+		TokenString:   {FGColor: theme.SuccessColor()},
+		TokenComment:  {FGColor: theme.DisabledColor(), Italic: true},
+		TokenNumber:   {FGColor: theme.WarningColor()},
+		TokenTypeName: {FGColor: theme.PrimaryColor()},
+		TokenFunction: {Bold: true},
+	}
+}
+
+// SetHighlighter sets the highlighter used for syntax highlighting and tokenizes the whole text. Tokens
+// are marked by tags named by TokenType.TagName, for which a styler using the token theme is added
+// unless there is already a styler with the same tag name. Edited paragraphs are tokenized again
+// automatically. Use nil to switch off syntax highlighting.
+func (z *Editor) SetHighlighter(h Highlighter) {
+	z.highlighter = h
+	if h != nil {
+		for t := TokenKeyword; int(t) < len(tokenTypeNames); t++ {
+			if !z.Styles.HasStyler(t.TagName()) {
+				z.Styles.AddStyler(z.tokenStyler(t))
+			}
+		}
+	}
+	z.highlightAll()
+	z.Refresh()
+}
+
+// Highlighter returns the current highlighter, nil if there is none.
+func (z *Editor) Highlighter() Highlighter {
+	return z.highlighter
+}
+
+// SetTokenTheme sets the styles of syntax highlighted tokens. The display is updated, so themes may be
+// swapped at runtime. If the theme is nil, DefaultTokenTheme is used, which follows changes of the
+// Fyne theme.
+func (z *Editor) SetTokenTheme(tt TokenTheme) {
+	z.tokenTheme = tt
+	z.Refresh()
+}
+
+// TokenTheme returns the current token theme, which is the default theme for the current Fyne theme
+// if none has been set.
+func (z *Editor) TokenTheme() TokenTheme {
+	if z.tokenTheme == nil {
+		return DefaultTokenTheme()
+	}
+	return z.tokenTheme
+}
+
+// tokenStyler returns the styler for tokens of the given type, which styles them by the token theme.
+func (z *Editor) tokenStyler(t TokenType) TagStyler {
+	return TagStyler{
+		TagName: t.TagName(),
+		StyleFunc: TagStyleFunc(func(tag Tag, c Cell) Cell {
+			tokenStyle, ok := z.TokenTheme()[t]
+			if !ok {
+				return c
+			}
+			style := c.Style
+			if tokenStyle.FGColor != nil {
+				style.FGColor = tokenStyle.FGColor
+			}
+			if tokenStyle.BGColor != nil {
+				style.BGColor = tokenStyle.BGColor
+			}
+			style.Bold = style.Bold || tokenStyle.Bold
+			style.Italic = style.Italic || tokenStyle.Italic
+			return Cell{Rune: c.Rune, Style: style}
+		}),
+	}
+}
+
+// highlightAll removes all token tags and tokenizes the whole text again.
+func (z *Editor) highlightAll() {
+	for t := TokenKeyword; int(t) < len(tokenTypeNames); t++ {
+		z.Tags.DeleteByName(t.TagName())
+	}
+	z.highlightStates = nil
+	if z.highlighter == nil || z.hex != nil {
+		return
+	}
+	z.highlightStates = make([]HighlightState, z.paragraphCount())
+	row := 0
+	for i := range z.highlightStates {
+		end, _ := z.tokenizeParagraph(row, i)
+		row = end + 1
+	}
+}
+
+// highlightEdit tokenizes the paragraphs affected by an edit at the given line again. Paragraphs
+// added by the edit follow the paragraph containing the line, and paragraphs joined by the edit are
+// merged into it. If the end state of the last edited paragraph has changed, e.g. because a block
+// comment was opened, the following paragraphs are tokenized again until a paragraph's end state
+// matches its cached end state.
+func (z *Editor) highlightEdit(line int) {
+	if z.highlighter == nil || z.hex != nil {
+		return
+	}
+	if z.highlightStates == nil {
+		z.highlightAll()
+		return
+	}
+	start := z.FindParagraphStart(SafePositiveValue(line, z.LastLine()), z.Config.HardLF)
+	p := z.paragraphIndex(start)
+	delta := z.paragraphCount() - len(z.highlightStates)
+	if delta > 0 {
+		z.highlightStates = slices.Insert(z.highlightStates, p+1, make([]HighlightState, delta)...)
+	} else if delta < 0 {
+		z.highlightStates = slices.Delete(z.highlightStates, p+1, min(p+1-delta, len(z.highlightStates)))
+	}
+	row := start
+	for i := p; i < len(z.highlightStates); i++ {
+		end, changed := z.tokenizeParagraph(row, i)
+		if !changed && i >= p+max(delta, 0) {
+			break
+		}
+		row = end + 1
+	}
+}
+
+// tokenizeParagraph tokenizes the paragraph starting at row, which is the paragraph with index idx,
+// replacing its token tags and storing its end state. It returns the last row of the paragraph and
+// true if the end state differs from the previously stored end state.
+func (z *Editor) tokenizeParagraph(row, idx int) (int, bool) {
+	end := z.FindParagraphEnd(row, z.Config.HardLF)
+	interval := CharInterval{Start: CharPos{Line: row, Column: 0}, End: CharPos{Line: end, Column: z.LastColumn(end)}}
+	if tags, ok := z.Tags.LookupRange(interval); ok {
+		for _, tag := range tags {
+			if tag != nil && strings.HasPrefix(tag.Name(), "_token_") {
+				z.Tags.Delete(tag)
+			}
+		}
+	}
+	var state HighlightState
+	if idx > 0 {
+		state = z.highlightStates[idx-1]
+	}
+	text := []rune(z.paragraphString(row, end))
+	tokens, state := z.highlighter.Tokenize(text, state)
+	changed := state != z.highlightStates[idx]
+	z.highlightStates[idx] = state
+	for _, token := range tokens {
+		from, to := max(token.Start, 0), min(token.End, len(text))
+		if token.Type == TokenText || from >= to {
+			continue
+		}
+		z.Tags.Upsert(z.Tags.CloneTag(NewTag(token.Type.TagName())),
+			CharInterval{Start: z.paragraphOffsetToPos(row, from), End: z.paragraphOffsetToPos(row, to-1)})
+	}
+	return end, changed
+}
+
+// paragraphCount returns the number of paragraphs in the text.
+func (z *Editor) paragraphCount() int {
+	return z.paragraphIndex(len(z.Rows))
+}
+
+// paragraphIndex returns the index of the paragraph starting at the given row, i.e., the number of
+// paragraphs ending before it.
+func (z *Editor) paragraphIndex(row int) int {
+	if z.paged != nil {
+		return min(row, len(z.Rows)) // rows of a custom buffer are not wrapped
+	}
+	n := 0
+	for i := 0; i < row && i < len(z.Rows); i++ {
+		if k := len(z.Rows[i]); k == 0 || z.Rows[i][k-1] != z.Config.SoftLF {
+			n++
+		}
+	}
+	return n
+}
+
+// GoHighlighter is a simple Highlighter for the Go programming language. It recognizes keywords,
+// predeclared types, function calls, numbers, strings, and comments, including multi-line raw strings
+// and block comments.
+type GoHighlighter struct{}
+
+// states of GoHighlighter at the end of a paragraph
+const (
+	goStateNormal = iota
+	goStateComment
+	goStateRawString
+)
+
+var goKeywords = map[string]bool{"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true, "range": true,
+	"return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true, "nil": true,
+	"true": true, "false": true, "iota": true}
+
+var goTypes = map[string]bool{"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true,
+	"complex128": true, "error": true, "float32": true, "float64": true, "int": true, "int8": true,
+	"int16": true, "int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true}
+
+// Tokenize implements Highlighter.
+func (h GoHighlighter) Tokenize(line []rune, state HighlightState) ([]Token, HighlightState) {
+	st, _ := state.(int)
+	var tokens []Token
+	i := 0
+	switch st {
+	case goStateComment:
+		end := indexRunes(line, 0, []rune("*/"))
+		if end < 0 {
+			return []Token{{Type: TokenComment, Start: 0, End: len(line)}}, goStateComment
+		}
+		tokens = append(tokens, Token{Type: TokenComment, Start: 0, End: end + 2})
+		i = end + 2
+	case goStateRawString:
+		end := indexRunes(line, 0, []rune("`"))
+		if end < 0 {
+			return []Token{{Type: TokenString, Start: 0, End: len(line)}}, goStateRawString
+		}
+		tokens = append(tokens, Token{Type: TokenString, Start: 0, End: end + 1})
+		i = end + 1
+	}
+	for i < len(line) {
+		c := line[i]
+		start := i
+		switch {
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return append(tokens, Token{Type: TokenComment, Start: i, End: len(line)}), goStateNormal
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			end := indexRunes(line, i+2, []rune("*/"))
+			if end < 0 {
+				return append(tokens, Token{Type: TokenComment, Start: i, End: len(line)}), goStateComment
+			}
+			i = end + 2
+			tokens = append(tokens, Token{Type: TokenComment, Start: start, End: i})
+		case c == '`':
+			end := indexRunes(line, i+1, []rune("`"))
+			if end < 0 {
+				return append(tokens, Token{Type: TokenString, Start: i, End: len(line)}), goStateRawString
+			}
+			i = end + 1
+			tokens = append(tokens, Token{Type: TokenString, Start: start, End: i})
+		case c == '"' || c == '\'':
+			i++
+			for i < len(line) && line[i] != c {
+				if line[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i = min(i+1, len(line))
+			tokens = append(tokens, Token{Type: TokenString, Start: start, End: i})
+		case unicode.IsDigit(c):
+			for i < len(line) && (unicode.IsLetter(line[i]) || unicode.IsDigit(line[i]) || line[i] == '.' || line[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, Token{Type: TokenNumber, Start: start, End: i})
+		case unicode.IsLetter(c) || c == '_':
+			for i < len(line) && (unicode.IsLetter(line[i]) || unicode.IsDigit(line[i]) || line[i] == '_') {
+				i++
+			}
+			word := string(line[start:i])
+			switch {
+			case goKeywords[word]:
+				tokens = append(tokens, Token{Type: TokenKeyword, Start: start, End: i})
+			case goTypes[word]:
+				tokens = append(tokens, Token{Type: TokenTypeName, Start: start, End: i})
+			case i < len(line) && line[i] == '(':
+				tokens = append(tokens, Token{Type: TokenFunction, Start: start, End: i})
+			}
+		case strings.ContainsRune("+-*/%&|^<>=!:", c):
+			i++
+			tokens = append(tokens, Token{Type: TokenOperator, Start: start, End: i})
+		default:
+			i++
+		}
+	}
+	return tokens, goStateNormal
+}
+
+// indexRunes returns the index of the first occurrence of sub in s at or after from, or -1 if there
+// is none.
+func indexRunes(s []rune, from int, sub []rune) int {
+	for i := from; i+len(sub) <= len(s); i++ {
+		if slices.Equal(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,118 @@
+package zedit
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// SelectionAsHTML returns the current selection as an HTML fragment, with the text styled as it is
+// displayed by the editor except for the selection highlighting itself. The text is wrapped in a
+// pre element and styled runs of text are put into span elements with inline CSS. The empty string
+// is returned if there is no selection.
+func (z *Editor) SelectionAsHTML() string {
+	sels := z.Selections()
+	if len(sels) == 0 {
+		return ""
+	}
+	marks := z.Tags.TagsByNameSorted(z.Config.SelectionTag.Name())
+	z.Tags.DeleteByName(z.Config.SelectionTag.Name())
+	defer func() {
+		for _, mark := range marks {
+			z.Tags.Upsert(mark.Tag, mark.Interval)
+		}
+	}()
+	var sb strings.Builder
+	sb.WriteString(`<pre style="font-family: monospace;">`)
+	for i, sel := range sels {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		z.writeHTML(&sb, sel)
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+// CopyAsHTML puts the HTML representation of the current selection obtained by SelectionAsHTML onto
+// the clipboard of the window containing the editor. Since Fyne's clipboard only supports plain text,
+// the HTML source is put onto the clipboard as text. Applications that can set multiple clipboard
+// formats should use SelectionAsHTML and CurrentSelectionText instead. Nothing is done if there is
+// no selection or no window containing the editor.
+func (z *Editor) CopyAsHTML() {
+	s := z.SelectionAsHTML()
+	if s == "" {
+		return
+	}
+	if cb := z.clipboard(); cb != nil {
+		cb.SetContent(s)
+	}
+}
+
+// writeHTML writes the styled text of the interval as HTML to sb. Hard line feeds are written as
+// newlines and soft line feeds are omitted.
+func (z *Editor) writeHTML(sb *strings.Builder, interval CharInterval) {
+	interval = interval.Sanitize(z.LastPos())
+	grid := z.RenderSlice(interval.Start.Line, interval.End.Line-interval.Start.Line+1)
+	css := ""
+	open := false
+	for i := range grid.Rows {
+		line := interval.Start.Line + i
+		for j, cell := range grid.Rows[i].Cells {
+			pos := CharPos{Line: line, Column: j}
+			if CmpPos(pos, interval.Start) < 0 || CmpPos(pos, interval.End) > 0 {
+				continue
+			}
+			if j == z.LastColumn(line) {
+				if cell.Rune != z.Config.SoftLF {
+					sb.WriteByte('\n')
+				}
+				continue
+			}
+			style := NewCellFromTextGridCell(cell).Style
+			if custom, ok := cell.Style.(*widget.CustomTextGridStyle); ok {
+				style.Bold, style.Italic = custom.TextStyle.Bold, custom.TextStyle.Italic
+			}
+			s := styleToCSS(style)
+			if s != css || !open {
+				if open {
+					sb.WriteString("</span>")
+				}
+				fmt.Fprintf(sb, `<span style="%s">`, s)
+				css = s
+				open = true
+			}
+			sb.WriteString(html.EscapeString(string(cell.Rune)))
+		}
+	}
+	if open {
+		sb.WriteString("</span>")
+	}
+}
+
+// styleToCSS returns the inline CSS for the given style.
+func styleToCSS(style Style) string {
+	var parts []string
+	if style.FGColor != nil {
+		parts = append(parts, "color: "+colorToCSS(style.FGColor))
+	}
+	if style.BGColor != nil {
+		parts = append(parts, "background-color: "+colorToCSS(style.BGColor))
+	}
+	if style.Bold {
+		parts = append(parts, "font-weight: bold")
+	}
+	if style.Italic {
+		parts = append(parts, "font-style: italic")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// colorToCSS returns the CSS hex notation of the given color, ignoring its alpha value.
+func colorToCSS(c color.Color) string {
+	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
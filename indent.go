@@ -0,0 +1,250 @@
+package zedit
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IndentStrategy determines the indentation of lines for automatic indentation. IndentFor returns
+// the indentation width of curLine in columns, given the previous paragraph prevLine (nil if there
+// is none). Both lines are passed without line feeds.
+type IndentStrategy interface {
+	IndentFor(prevLine, curLine []rune) int
+}
+
+// CStyleIndent is an IndentStrategy for languages with C-like brackets. A line is indented one level
+// deeper than the previous line if the previous line ends in an opening bracket, and one level less
+// if it starts with a closing bracket. Otherwise, it has the same indentation as the previous line.
+type CStyleIndent struct {
+	Width int // the width of one indentation level (if 0 or below, 4 is used)
+}
+
+// IndentFor implements IndentStrategy.
+func (c CStyleIndent) IndentFor(prevLine, curLine []rune) int {
+	width := c.Width
+	if width <= 0 {
+		width = 4
+	}
+	indent := 0
+	for _, r := range prevLine {
+		if r == '\t' {
+			indent += width
+		} else if r == ' ' {
+			indent++
+		} else {
+			break
+		}
+	}
+	prev := strings.TrimRightFunc(string(prevLine), unicode.IsSpace)
+	if r, _ := utf8.DecodeLastRuneInString(prev); IsLeftParen(r) {
+		indent += width
+	}
+	cur := strings.TrimLeftFunc(string(curLine), unicode.IsSpace)
+	if r, _ := utf8.DecodeRuneInString(cur); IsRightParen(r) {
+		indent -= width
+	}
+	return max(0, indent)
+}
+
+// reindentParagraph replaces the leading whitespace of the paragraph starting at row by the
+// indentation determined by Config.IndentStrategy, adjusting the caret if it is in the paragraph.
+func (z *Editor) reindentParagraph(row int) {
+	if z.Config.IndentStrategy == nil || row < 0 || row > z.LastLine() {
+		return
+	}
+	var prev []rune
+	if row > 0 {
+		prev = []rune(z.paragraphString(z.FindParagraphStart(row-1, z.Config.HardLF), row-1))
+	}
+	n := z.Config.IndentStrategy.IndentFor(prev, []rune(z.ParagraphText(row)))
+	k := 0
+	for k < z.LastColumn(row) && (z.row(row)[k] == ' ' || z.row(row)[k] == '\t') {
+		k++
+	}
+	if n == k && !strings.ContainsRune(string(z.row(row)[:k]), '\t') {
+		return
+	}
+	caret := z.caretPos
+	if k > 0 {
+		z.Delete(CharInterval{Start: CharPos{Line: row, Column: 0}, End: CharPos{Line: row, Column: k - 1}})
+	}
+	if n > 0 {
+		z.Insert([]rune(strings.Repeat(" ", n)), CharPos{Line: row, Column: 0})
+	}
+	if caret.Line == row {
+		caret.Column = max(0, caret.Column-k) + n
+	}
+	line := SafePositiveValue(caret.Line, z.LastLine())
+	z.SetCaret(CharPos{Line: line, Column: SafePositiveValue(caret.Column, z.LastColumn(line))})
+}
+
+// reindentPasted reindents the paragraphs of a text pasted at start up to the paragraph containing
+// the caret if Config.IndentStrategy is set, so that the pasted lines are indented according to the
+// brackets around them. The first paragraph is only reindented if the text was pasted at its start
+// or after its indentation.
+func (z *Editor) reindentPasted(start CharPos) {
+	if z.Config.IndentStrategy == nil {
+		return
+	}
+	row := z.FindParagraphStart(start.Line, z.Config.HardLF)
+	if row != start.Line || strings.TrimSpace(string(z.row(row)[:min(start.Column, len(z.row(row)))])) != "" {
+		row = z.FindParagraphEnd(start.Line, z.Config.HardLF) + 1
+	}
+	for row <= z.caretPos.Line && row <= z.LastLine() {
+		z.reindentParagraph(row)
+		row = z.FindParagraphEnd(row, z.Config.HardLF) + 1
+	}
+}
+
+// maybeReindentClosing reindents the current paragraph if Config.IndentStrategy is set and the
+// rune r, which has just been typed, is a closing bracket preceded only by whitespace.
+func (z *Editor) maybeReindentClosing(r rune) {
+	if z.Config.IndentStrategy == nil || !IsRightParen(r) {
+		return
+	}
+	row := z.FindParagraphStart(z.caretPos.Line, z.Config.HardLF)
+	if row != z.caretPos.Line {
+		return
+	}
+	if strings.TrimSpace(string(z.row(row)[:min(z.caretPos.Column, len(z.row(row)))])) != string(r) {
+		return
+	}
+	z.reindentParagraph(row)
+}
+
+// tabWidth returns the configured tab width, or defaultTabWidth if none is set.
+func (z *Editor) tabWidth() int {
+	if z.Config.TabWidth <= 0 {
+		return defaultTabWidth
+	}
+	return z.Config.TabWidth
+}
+
+// indentUnit returns the text inserted for one indentation level, which is a tab or, if
+// Config.SoftTabs is true, as many spaces as the tab width.
+func (z *Editor) indentUnit() []rune {
+	if z.Config.SoftTabs {
+		return []rune(strings.Repeat(" ", z.tabWidth()))
+	}
+	return []rune{'\t'}
+}
+
+// Tab implements the tab key. If the selection spans multiple lines, all paragraphs in it are indented
+// by one level. Otherwise, a tab or spaces are inserted at the caret, replacing the selection if
+// Config.TypeOverSelection is true.
+func (z *Editor) Tab() {
+	if z.Config.ReadOnly {
+		return
+	}
+	if sel, ok := z.CurrentSelection(); ok && sel.Start.Line != sel.End.Line {
+		z.Indent(sel.Start.Line, sel.End.Line)
+		return
+	}
+	if !z.maybeDeleteSelection() {
+		return
+	}
+	unit := z.indentUnit()
+	pos := z.caretPos
+	z.Insert(unit, pos)
+	z.SetCaret(z.advancePos(pos, len(unit)))
+}
+
+// Outdent implements Shift+Tab, it removes one indentation level from the paragraphs in the selection,
+// or from the paragraph containing the caret if there is no selection.
+func (z *Editor) Outdent() {
+	if z.Config.ReadOnly {
+		return
+	}
+	if sel, ok := z.CurrentSelection(); ok {
+		z.Unindent(sel.Start.Line, sel.End.Line)
+		return
+	}
+	z.Unindent(z.caretPos.Line, z.caretPos.Line)
+}
+
+// Indent indents all paragraphs from the one containing startLine to the one containing endLine by
+// one level.
+func (z *Editor) Indent(startLine, endLine int) {
+	z.changeIndentation(startLine, endLine, func(row int) {
+		z.Insert(z.indentUnit(), CharPos{Line: row, Column: 0})
+	})
+}
+
+// Unindent removes one level of indentation from all paragraphs from the one containing startLine to
+// the one containing endLine. A level is a tab or up to tab width spaces.
+func (z *Editor) Unindent(startLine, endLine int) {
+	z.changeIndentation(startLine, endLine, func(row int) {
+		n := 0
+		if z.LastColumn(row) > 0 && z.row(row)[0] == '\t' {
+			n = 1
+		}
+		for n < z.tabWidth() && n < z.LastColumn(row) && z.row(row)[n] == ' ' {
+			n++
+		}
+		if n > 0 {
+			z.Delete(CharInterval{Start: CharPos{Line: row, Column: 0}, End: CharPos{Line: row, Column: n - 1}})
+		}
+	})
+}
+
+// changeIndentation calls fn with the start row of each paragraph from the one containing startLine to
+// the one containing endLine, from the last to the first paragraph so that rewrapping does not affect
+// the rows of paragraphs not yet changed. The caret is kept at the same text position.
+func (z *Editor) changeIndentation(startLine, endLine int, fn func(row int)) {
+	startLine = SafePositiveValue(startLine, z.LastLine())
+	endLine = SafePositiveValue(endLine, z.LastLine())
+	var starts []int
+	row := z.FindParagraphStart(startLine, z.Config.HardLF)
+	for row <= endLine {
+		starts = append(starts, row)
+		row = z.FindParagraphEnd(row, z.Config.HardLF) + 1
+	}
+	caret := z.CreateAnchor(z.caretPos)
+	defer caret.Release()
+	for i := len(starts) - 1; i >= 0; i-- {
+		fn(starts[i])
+	}
+	if pos, ok := caret.Pos(); ok {
+		z.SetCaret(pos)
+	}
+	z.Refresh()
+}
+
+// ToggleComment comments or uncomments the paragraph containing the caret, or all paragraphs touched
+// by the selection. A paragraph starting with prefix after its indentation is uncommented by removing
+// the prefix, any other paragraph that is not blank is commented by inserting the prefix after its
+// indentation. The selection is preserved.
+func (z *Editor) ToggleComment(prefix string) {
+	p := []rune(prefix)
+	if z.Config.ReadOnly || len(p) == 0 {
+		return
+	}
+	startLine, endLine := z.caretPos.Line, z.caretPos.Line
+	var selStart, selEnd *Anchor
+	if sel, ok := z.CurrentSelection(); ok {
+		startLine, endLine = sel.Start.Line, sel.End.Line
+		selStart, selEnd = z.CreateAnchor(sel.Start), z.CreateAnchor(sel.End)
+		defer selStart.Release()
+		defer selEnd.Release()
+	}
+	z.changeIndentation(startLine, endLine, func(row int) {
+		col := z.firstNonSpaceColumn(row)
+		if col >= z.LastColumn(row) {
+			return
+		}
+		end := min(col+len(p), z.LastColumn(row))
+		if string(z.row(row)[col:end]) == prefix {
+			z.Delete(CharInterval{Start: CharPos{Line: row, Column: col}, End: CharPos{Line: row, Column: end - 1}})
+			return
+		}
+		z.Insert(p, CharPos{Line: row, Column: col})
+	})
+	if selStart != nil {
+		start, ok1 := selStart.Pos()
+		end, ok2 := selEnd.Pos()
+		if ok1 && ok2 {
+			z.Select(CharInterval{Start: start, End: end})
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package zedit
+
+import (
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestCStyleIndentFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur string
+		want      int
+	}{
+		{"first line", "", "x", 0},
+		{"same level", "    x := 1", "y", 4},
+		{"after opening bracket", "if x {", "y", 4},
+		{"after opening bracket and space", "  f(  ", "y", 6},
+		{"closing bracket", "    y", "}", 0},
+		{"closing bracket after opening", "\tif x {", "  }", 4},
+		{"tab indentation", "\t\tx", "y", 8},
+		{"non-ASCII at end", "    s := \"ä\"", "y", 4},
+		{"non-ASCII at start", "    x", "ö()", 4},
+		{"non-ASCII before bracket", "    m := map[string]int{\"ü\": 1, ", "}", 0},
+		{"bracket after non-ASCII", "    ü := []int{", "1", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (CStyleIndent{}).IndentFor([]rune(tt.prev), []rune(tt.cur)); got != tt.want {
+				t.Errorf("IndentFor(%q, %q) = %d, want %d", tt.prev, tt.cur, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasteReindents(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	w := test.NewTempWindow(t, z)
+	z.Config.IndentStrategy = CStyleIndent{Width: 4}
+	w.Clipboard().SetContent("a()\nif b {\nc()\n}\n")
+	var got string
+	var caret CharPos
+	z.Do(func() {
+		z.SetText("func f() {\n}")
+		z.SetCaret(CharPos{Line: 1, Column: 0})
+		z.Paste()
+		got = z.Text()
+		caret = z.caretPos
+	})
+	want := strings.Join([]string{
+		"func f() {",
+		"    a()",
+		"    if b {",
+		"        c()",
+		"    }",
+		"}",
+		"",
+	}, "\n")
+	if got != want {
+		t.Errorf("text after paste = %q, want %q", got, want)
+	}
+	if caret != (CharPos{Line: 5, Column: 0}) {
+		t.Errorf("caret = %v, want 5:0", caret)
+	}
+}
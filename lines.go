@@ -0,0 +1,187 @@
+package zedit
+
+import "strings"
+
+// selectedParagraphs returns the first and last row of the paragraphs touched by the selection, or of
+// the paragraph containing the caret if there is no selection.
+func (z *Editor) selectedParagraphs() (int, int) {
+	startLine, endLine := z.caretPos.Line, z.caretPos.Line
+	if sel, ok := z.CurrentSelection(); ok {
+		startLine, endLine = sel.Start.Line, sel.End.Line
+	}
+	return z.FindParagraphStart(startLine, z.Config.HardLF), z.FindParagraphEnd(endLine, z.Config.HardLF)
+}
+
+// MoveLineUp swaps the paragraph containing the caret, or the paragraphs touched by the selection, with
+// the paragraph above it. The caret, the selection, and tags move with the text. Nothing is done at the
+// start of the text.
+func (z *Editor) MoveLineUp() {
+	start, end := z.selectedParagraphs()
+	if z.Config.ReadOnly || start == 0 {
+		return
+	}
+	z.swapRows(z.FindParagraphStart(start-1, z.Config.HardLF), start, end+1)
+}
+
+// MoveLineDown swaps the paragraph containing the caret, or the paragraphs touched by the selection, with
+// the paragraph below it. The caret, the selection, and tags move with the text. Nothing is done at the
+// end of the text.
+func (z *Editor) MoveLineDown() {
+	start, end := z.selectedParagraphs()
+	if z.Config.ReadOnly || end >= z.LastLine() {
+		return
+	}
+	z.swapRows(start, end+1, z.FindParagraphEnd(end+1, z.Config.HardLF)+1)
+}
+
+// DuplicateLine inserts a copy of the paragraph containing the caret, or of the paragraphs touched by the
+// selection, below them and puts the caret at the same position in the copy. Tags are not copied.
+func (z *Editor) DuplicateLine() {
+	start, end := z.selectedParagraphs()
+	if z.Config.ReadOnly || z.IsProtected(CharPos{Line: end, Column: z.LastColumn(end)}) {
+		return
+	}
+	var paragraphs []string
+	for row := start; row <= end; row = z.FindParagraphEnd(row, z.Config.HardLF) + 1 {
+		paragraphs = append(paragraphs, z.paragraphString(row, z.FindParagraphEnd(row, z.Config.HardLF)))
+	}
+	caret := z.caretPos
+	z.RemoveSelection()
+	z.SetCaret(CharPos{Line: end, Column: z.LastColumn(end)})
+	z.insertText("\n" + strings.Join(paragraphs, "\n"))
+	line := SafePositiveValue(caret.Line+end-start+1, z.LastLine())
+	z.SetCaret(CharPos{Line: line, Column: SafePositiveValue(caret.Column, z.LastColumn(line))})
+	z.scrollToCaret()
+}
+
+// swapRows swaps the rows from start to mid (exclusive) with the rows from mid to end (exclusive),
+// where both ranges consist of whole paragraphs. Tags within one of the ranges, the caret, and the
+// highlighting are moved along.
+func (z *Editor) swapRows(start, mid, end int) {
+	if start >= mid || mid >= end || end > len(z.Rows) || z.isProtectedRange(z.linesInterval(start, end-start)) {
+		return
+	}
+	z.materialize()
+	up, down := mid-start, end-mid
+	tags, _ := z.Tags.LookupRange(z.linesInterval(start, end-start))
+	for _, tag := range tags {
+		if tag == nil {
+			continue
+		}
+		interval, ok := z.Tags.Lookup(tag)
+		if !ok {
+			continue
+		}
+		delta := 0
+		switch {
+		case interval.Start.Line >= start && interval.End.Line < mid:
+			delta = down
+		case interval.Start.Line >= mid && interval.End.Line < end:
+			delta = -up
+		}
+		if delta != 0 {
+			interval.Start.Line += delta
+			interval.End.Line += delta
+			z.Tags.Upsert(tag, interval)
+		}
+	}
+	rows := make([][]rune, 0, end-start)
+	rows = append(rows, z.Rows[mid:end]...)
+	rows = append(rows, z.Rows[start:mid]...)
+	copy(z.Rows[start:end], rows)
+	for a := range z.anchors {
+		switch {
+		case a.pos.Line >= start && a.pos.Line < mid:
+			a.pos.Line += down
+		case a.pos.Line >= mid && a.pos.Line < end:
+			a.pos.Line -= up
+		}
+	}
+	switch {
+	case z.caretPos.Line >= start && z.caretPos.Line < mid:
+		z.caretPos.Line += down
+	case z.caretPos.Line >= mid && z.caretPos.Line < end:
+		z.caretPos.Line -= up
+	}
+	for row := start; row < end; row = z.FindParagraphEnd(row, z.Config.HardLF) + 1 {
+		z.highlightEdit(row)
+	}
+	z.markChanged()
+	z.notifyChange(Change{Interval: z.linesInterval(start, end-start), Inserted: z.runeCount(start, end),
+		Removed: z.runeCount(start, end)})
+	z.scrollToCaret()
+}
+
+// ReflowParagraph wraps the paragraph containing the caret at the given fill column by replacing
+// spaces with hard line feeds, so that no resulting paragraph is longer than fillColumn unless it
+// contains a word that is longer. Unlike soft wrapping, the line breaks are part of the text. Tags
+// and the caret move with the text.
+func (z *Editor) ReflowParagraph(fillColumn int) {
+	if z.Config.ReadOnly || fillColumn < 1 {
+		return
+	}
+	start := z.FindParagraphStart(z.caretPos.Line, z.Config.HardLF)
+	end := z.FindParagraphEnd(z.caretPos.Line, z.Config.HardLF)
+	if z.isProtectedRange(z.linesInterval(start, end-start+1)) {
+		return
+	}
+	breaks := reflowBreaks([]rune(z.paragraphString(start, end)), fillColumn)
+	if len(breaks) == 0 {
+		return
+	}
+	caret := z.CreateAnchor(z.caretPos)
+	defer caret.Release()
+	strategy := z.Config.IndentStrategy
+	z.Config.IndentStrategy = nil
+	defer func() { z.Config.IndentStrategy = strategy }()
+	for i := len(breaks) - 1; i >= 0; i-- {
+		pos := z.paragraphOffsetToPos(start, breaks[i])
+		z.Delete(CharInterval{Start: pos, End: pos})
+		z.SetCaret(pos)
+		z.insertLineBreak()
+	}
+	if pos, ok := caret.Pos(); ok {
+		z.SetCaret(pos)
+	}
+	z.scrollToCaret()
+	z.Refresh()
+}
+
+// reflowBreaks returns the offsets of the spaces in text that must be replaced by line breaks so that
+// the lines are no longer than fillColumn, breaking at the last space that fits.
+func reflowBreaks(text []rune, fillColumn int) []int {
+	var breaks []int
+	lineStart, lastSpace := 0, -1
+	for i, c := range text {
+		if c == ' ' {
+			lastSpace = i
+		}
+		if i-lineStart >= fillColumn && lastSpace > lineStart {
+			breaks = append(breaks, lastSpace)
+			lineStart = lastSpace + 1
+		}
+	}
+	return breaks
+}
+
+// TrimTrailingWhitespace removes spaces and tabs at the end of each paragraph. Since a paragraph may
+// be soft wrapped over several rows, whitespace is only removed before hard line feeds, not before
+// soft line feeds. Tags are adjusted as for Delete.
+func (z *Editor) TrimTrailingWhitespace() {
+	if z.Config.ReadOnly || z.hex != nil {
+		return
+	}
+	for end := z.LastLine(); end >= 0; {
+		start := z.FindParagraphStart(end, z.Config.HardLF)
+		text := []rune(z.paragraphString(start, end))
+		n := len(text)
+		for n > 0 && (text[n-1] == ' ' || text[n-1] == '\t') {
+			n--
+		}
+		if n < len(text) {
+			z.Delete(CharInterval{Start: z.paragraphOffsetToPos(start, n),
+				End: z.paragraphOffsetToPos(start, len(text)-1)})
+		}
+		end = start - 1
+	}
+}
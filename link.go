@@ -0,0 +1,173 @@
+package zedit
+
+// LinkID identifies a pair of linked ranges created by LinkRanges.
+type LinkID int
+
+// rangeLink is a pair of linked ranges, whose intervals are tracked by tags.
+type rangeLink struct {
+	a, b Tag
+}
+
+// LinkRanges links the two given ranges so that edits within one of them are applied in parallel to
+// the other one, which is useful for renaming paired constructs such as opening and closing tags.
+// Both ranges must be within a single line each and must not overlap. If they do not satisfy these
+// conditions, 0 is returned and nothing is linked. The link is removed when one of the ranges is
+// deleted entirely or its text is split across lines, e.g. by word wrapping.
+func (z *Editor) LinkRanges(a, b CharInterval) LinkID {
+	a = a.Sanitize(z.LastPos())
+	b = b.Sanitize(z.LastPos())
+	if a.Start.Line != a.End.Line || b.Start.Line != b.End.Line || !a.OutsideOf(b) {
+		return 0
+	}
+	if !z.ValidPos(a.Start) || !z.ValidPos(b.Start) {
+		return 0
+	}
+	if z.links == nil {
+		z.links = make(map[LinkID]*rangeLink)
+	}
+	z.nextLinkID++
+	link := &rangeLink{a: z.Tags.CloneTag(NewTag("_link")), b: z.Tags.CloneTag(NewTag("_link"))}
+	z.Tags.Upsert(link.a, a)
+	z.Tags.Upsert(link.b, b)
+	z.links[z.nextLinkID] = link
+	return z.nextLinkID
+}
+
+// UnlinkRanges removes the link with the given id. The text is not changed.
+func (z *Editor) UnlinkRanges(id LinkID) {
+	link, ok := z.links[id]
+	if !ok {
+		return
+	}
+	z.Tags.Delete(link.a)
+	z.Tags.Delete(link.b)
+	delete(z.links, id)
+}
+
+// LinkedRanges returns the current intervals of the linked ranges with the given id, and false if
+// there is no such link.
+func (z *Editor) LinkedRanges(id LinkID) (CharInterval, CharInterval, bool) {
+	link, ok := z.links[id]
+	if !ok {
+		return CharInterval{}, CharInterval{}, false
+	}
+	a, ok1 := z.Tags.Lookup(link.a)
+	b, ok2 := z.Tags.Lookup(link.b)
+	if !ok1 || !ok2 {
+		return CharInterval{}, CharInterval{}, false
+	}
+	return a, b, true
+}
+
+// findLink returns the link and the intervals of its source and partner range if the given interval
+// lies within one of the ranges of a link. If extend is true, the position directly after a range
+// also counts as being within it. Links whose ranges are no longer valid are removed.
+func (z *Editor) findLink(interval CharInterval, extend bool) (*rangeLink, CharInterval, CharInterval, bool) {
+	for id, link := range z.links {
+		a, b, ok := z.LinkedRanges(id)
+		if !ok || a.Start.Line != a.End.Line || b.Start.Line != b.End.Line {
+			z.UnlinkRanges(id)
+			continue
+		}
+		if withinRange(interval, a, extend) {
+			return link, a, b, true
+		}
+		if withinRange(interval, b, extend) {
+			return link, b, a, true
+		}
+	}
+	return nil, CharInterval{}, CharInterval{}, false
+}
+
+// withinRange returns true if interval is on the line of the single-line interval r and within its
+// columns. If extend is true, the column after r also counts as being within it.
+func withinRange(interval, r CharInterval, extend bool) bool {
+	if interval.Start.Line != r.Start.Line || interval.End.Line != r.Start.Line {
+		return false
+	}
+	end := r.End.Column
+	if extend {
+		end++
+	}
+	return interval.Start.Column >= r.Start.Column && interval.End.Column <= end
+}
+
+// insertLinked inserts r at pos and in parallel into the partner range if pos is within a linked
+// range. It returns false if pos is not within a linked range, in which case nothing is done.
+func (z *Editor) insertLinked(r []rune, pos CharPos) bool {
+	if z.mirroring || len(z.links) == 0 {
+		return false
+	}
+	link, src, dst, ok := z.findLink(CharInterval{Start: pos, End: pos}, true)
+	if !ok {
+		return false
+	}
+	dpos := CharPos{Line: dst.Start.Line, Column: dst.Start.Column + pos.Column - src.Start.Column}
+	z.mirroring = true
+	defer func() { z.mirroring = false }()
+	if CmpPos(dpos, pos) > 0 {
+		z.Insert(r, dpos)
+		z.Insert(r, pos)
+	} else {
+		z.Insert(r, pos)
+		caret := z.caretPos
+		z.Insert(r, dpos)
+		if caret.Line == dpos.Line {
+			caret.Column += len(r)
+		}
+		z.caretPos = caret
+	}
+	// Insertions within an interval do not extend it, so both ranges are extended here.
+	z.extendLinkedRange(link.a, len(r))
+	z.extendLinkedRange(link.b, len(r))
+	return true
+}
+
+// extendLinkedRange extends the end of the range tracked by tag by n columns.
+func (z *Editor) extendLinkedRange(tag Tag, n int) {
+	interval, ok := z.Tags.Lookup(tag)
+	if !ok {
+		return
+	}
+	interval.End.Column += n
+	z.Tags.Upsert(tag, interval)
+}
+
+// deleteLinked deletes fromTo and the corresponding part of the partner range if fromTo is within
+// a linked range. It returns false if fromTo is not within a linked range, in which case nothing is
+// done. If fromTo covers a whole linked range, the link is removed and false is returned.
+func (z *Editor) deleteLinked(fromTo CharInterval) bool {
+	if z.mirroring || len(z.links) == 0 {
+		return false
+	}
+	link, src, dst, ok := z.findLink(fromTo, false)
+	if !ok {
+		return false
+	}
+	if fromTo.Start.Column == src.Start.Column && fromTo.End.Column == src.End.Column {
+		for id := range z.links {
+			if z.links[id] == link {
+				z.UnlinkRanges(id)
+			}
+		}
+		return false
+	}
+	offset := fromTo.Start.Column - src.Start.Column
+	dFromTo := CharInterval{Start: CharPos{Line: dst.Start.Line, Column: dst.Start.Column + offset},
+		End: CharPos{Line: dst.Start.Line, Column: dst.Start.Column + offset + fromTo.End.Column - fromTo.Start.Column}}
+	if dFromTo.End.Column > dst.End.Column {
+		return false
+	}
+	z.mirroring = true
+	defer func() { z.mirroring = false }()
+	if CmpPos(dFromTo.Start, fromTo.Start) > 0 {
+		caret := z.caretPos
+		z.Delete(dFromTo)
+		z.caretPos = caret
+		z.Delete(fromTo)
+	} else {
+		z.Delete(fromTo)
+		z.Delete(dFromTo)
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package zedit
+
+// Metrics contains counts for a status bar, as returned by TextMetrics and SelectionMetrics.
+type Metrics struct {
+	Lines      int // number of rows
+	Paragraphs int // number of paragraphs
+	Words      int // number of maximal runs of runes for which IsWordRune returns true
+	Chars      int // number of runes, not counting line feeds
+}
+
+// LineCount returns the number of rows of the text, where each soft wrapped row counts as a line.
+func (z *Editor) LineCount() int {
+	return len(z.Rows)
+}
+
+// ParagraphCount returns the number of paragraphs of the text. It is the same as ParaCount.
+func (z *Editor) ParagraphCount() int {
+	return z.ParaCount()
+}
+
+// WordCount returns the number of words in the text. Words are delimited by runes for which
+// IsWordRune returns false and by hard line feeds, but not by soft line feeds.
+func (z *Editor) WordCount() int {
+	return z.TextMetrics().Words
+}
+
+// CharCount returns the number of runes in the text, not counting line feeds.
+func (z *Editor) CharCount() int {
+	return z.TextMetrics().Chars
+}
+
+// TextMetrics returns the metrics of the whole text. It takes time linear in the size of the text.
+func (z *Editor) TextMetrics() Metrics {
+	return z.metrics(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: z.LastPos()})
+}
+
+// SelectionMetrics returns the metrics of the current selection, or zero metrics if there is none.
+// For a block selection, the metrics of all of its lines are added up.
+func (z *Editor) SelectionMetrics() Metrics {
+	var m Metrics
+	for _, sel := range z.Selections() {
+		sm := z.metrics(sel)
+		m.Lines += sm.Lines
+		m.Paragraphs += sm.Paragraphs
+		m.Words += sm.Words
+		m.Chars += sm.Chars
+	}
+	return m
+}
+
+// metrics returns the metrics of the given interval. Rows and paragraphs are counted if the interval
+// touches them.
+func (z *Editor) metrics(interval CharInterval) Metrics {
+	interval = interval.Sanitize(z.LastPos())
+	m := Metrics{Lines: interval.End.Line - interval.Start.Line + 1}
+	if len(z.Rows) == 0 {
+		return m
+	}
+	m.Paragraphs = 1
+	inWord := false
+	for line := interval.Start.Line; line <= interval.End.Line; line++ {
+		row := z.row(line)
+		from, to := 0, len(row)-1
+		if line == interval.Start.Line {
+			from = interval.Start.Column
+		}
+		if line == interval.End.Line {
+			to = min(to, interval.End.Column)
+		}
+		for col := from; col <= to; col++ {
+			if col == len(row)-1 {
+				if row[col] != z.Config.SoftLF {
+					inWord = false
+					if line < interval.End.Line {
+						m.Paragraphs++
+					}
+				}
+				continue
+			}
+			m.Chars++
+			if IsWordRune(row[col]) {
+				if !inWord {
+					m.Words++
+				}
+				inWord = true
+			} else {
+				inWord = false
+			}
+		}
+	}
+	return m
+}
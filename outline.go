@@ -0,0 +1,85 @@
+package zedit
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// OutlineItem is an entry of a document outline such as a heading or a function definition.
+type OutlineItem struct {
+	Title string  // the title of the entry
+	Pos   CharPos // the position at which the match starts
+	Para  int     // the paragraph number of the entry, starting with 1
+}
+
+// BuildOutline scans the text paragraph by paragraph and returns an outline item for each paragraph
+// that matches the pattern, in document order. The title of an item is obtained by calling
+// nameForMatch with the match and its submatches as returned by regexp.FindStringSubmatch. If
+// nameForMatch is nil, the whole match is used as title. For example, a pattern like `^#+\s+(.*)`
+// with a function returning the first submatch yields an outline of Markdown headings.
+func (z *Editor) BuildOutline(pattern *regexp.Regexp, nameForMatch func([]string) string) []OutlineItem {
+	result := make([]OutlineItem, 0)
+	para := 0
+	for row := 0; row <= z.LastLine(); {
+		end := z.FindParagraphEnd(row, z.Config.HardLF)
+		para++
+		text := z.paragraphString(row, end)
+		loc := pattern.FindStringSubmatchIndex(text)
+		if loc != nil {
+			matches := make([]string, len(loc)/2)
+			for i := range matches {
+				if loc[2*i] >= 0 {
+					matches[i] = text[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			title := matches[0]
+			if nameForMatch != nil {
+				title = nameForMatch(matches)
+			}
+			offset := utf8.RuneCountInString(text[:loc[0]])
+			result = append(result, OutlineItem{Title: title, Pos: z.paragraphOffsetToPos(row, offset), Para: para})
+		}
+		row = end + 1
+	}
+	return result
+}
+
+// paragraphOffsetToPos returns the position of the rune at the given offset into the paragraph
+// starting at row startRow, not counting line feeds. Offsets beyond the paragraph are mapped to
+// the position of the paragraph's final line feed.
+func (z *Editor) paragraphOffsetToPos(startRow, offset int) CharPos {
+	end := z.FindParagraphEnd(startRow, z.Config.HardLF)
+	for i := startRow; i <= end; i++ {
+		n := max(0, len(z.row(i))-1)
+		if offset < n || i == end {
+			return CharPos{Line: i, Column: min(offset, n)}
+		}
+		offset -= n
+	}
+	return CharPos{Line: end, Column: z.LastColumn(end)}
+}
+
+// posToParagraphOffset returns the index of the paragraph containing pos and the offset of pos into
+// the paragraph, not counting line feeds. It is the inverse of paragraphOffsetToPos.
+func (z *Editor) posToParagraphOffset(pos CharPos) (int, int) {
+	start := z.FindParagraphStart(pos.Line, z.Config.HardLF)
+	offset := pos.Column
+	for i := start; i < pos.Line; i++ {
+		offset += max(0, len(z.row(i))-1)
+	}
+	return z.paragraphIndex(start), offset
+}
+
+// paragraphStartRow returns the first row of the paragraph with the given 0-based index and true, or
+// the last row and false if there is no such paragraph.
+func (z *Editor) paragraphStartRow(para int) (int, bool) {
+	row := 0
+	for n := 0; n < para; n++ {
+		end := z.FindParagraphEnd(row, z.Config.HardLF)
+		if end >= z.LastLine() {
+			return z.LastLine(), false
+		}
+		row = end + 1
+	}
+	return row, true
+}
@@ -0,0 +1,43 @@
+package zedit
+
+import "testing"
+
+func TestOverlapping(t *testing.T) {
+	iv := func(l1, c1, l2, c2 int) CharInterval {
+		return CharInterval{Start: CharPos{Line: l1, Column: c1}, End: CharPos{Line: l2, Column: c2}}
+	}
+	tests := []struct {
+		name string
+		a, b CharInterval
+		want bool
+	}{
+		{"disjoint", iv(0, 0, 0, 3), iv(0, 6, 0, 9), false},
+		{"adjacent", iv(0, 0, 0, 4), iv(0, 5, 0, 9), false},
+		{"touching at one position", iv(0, 0, 0, 5), iv(0, 5, 0, 9), true},
+		{"partial overlap", iv(0, 0, 0, 6), iv(0, 4, 0, 9), true},
+		{"containment", iv(0, 0, 0, 9), iv(0, 3, 0, 5), true},
+		{"equal", iv(1, 2, 1, 7), iv(1, 2, 1, 7), true},
+		{"zero-length inside", iv(0, 4, 0, 4), iv(0, 0, 0, 9), true},
+		{"zero-length at start", iv(0, 0, 0, 0), iv(0, 0, 0, 9), true},
+		{"zero-length at end", iv(0, 9, 0, 9), iv(0, 0, 0, 9), true},
+		{"zero-length before", iv(0, 2, 0, 2), iv(0, 3, 0, 9), false},
+		{"zero-length after", iv(0, 10, 0, 10), iv(0, 3, 0, 9), false},
+		{"zero-length equal", iv(2, 3, 2, 3), iv(2, 3, 2, 3), true},
+		{"zero-length different", iv(2, 3, 2, 3), iv(2, 4, 2, 4), false},
+		{"multi-line partial overlap", iv(0, 5, 2, 3), iv(2, 0, 4, 1), true},
+		{"multi-line containment", iv(0, 5, 5, 0), iv(2, 0, 3, 9), true},
+		{"multi-line on different lines", iv(0, 5, 1, 3), iv(2, 0, 4, 1), false},
+		{"multi-line end column before start column", iv(0, 5, 1, 3), iv(1, 4, 3, 0), false},
+		{"multi-line with start column after end column", iv(0, 8, 2, 1), iv(1, 0, 1, 2), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Overlapping(tt.b); got != tt.want {
+				t.Errorf("%v.Overlapping(%v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := tt.b.Overlapping(tt.a); got != tt.want {
+				t.Errorf("%v.Overlapping(%v) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
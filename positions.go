@@ -70,7 +70,7 @@ func (c CharInterval) Contains(pos CharPos) bool {
 // Overlapping returns true if the char interval is overlapping in any way with the interval passed as
 // argument, flase otherwise. c1.Overlapping(c2) and c2.Overlapping(c1) are equivalent.
 func (c1 CharInterval) Overlapping(c2 CharInterval) bool {
-	return !c2.OutsideOf(c2)
+	return !c1.OutsideOf(c2)
 }
 
 // Lines returns the number of lines this interval spans, including start and end line.
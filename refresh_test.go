@@ -0,0 +1,146 @@
+package zedit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/exp/slices"
+)
+
+// newRefreshTestEditor returns an editor with an 80×40 viewport displaying a text with highlighted
+// words, whose refreshes are made when they are requested.
+func newRefreshTestEditor(tb testing.TB) *Editor {
+	z := newTestEditor(tb, 80, 40)
+	var sb strings.Builder
+	for i := range 1000 {
+		fmt.Fprintf(&sb, "%d: the quick brown fox jumps over the lazy dog\n", i)
+	}
+	z.Do(func() {
+		z.Config.MinRefreshInterval = 0
+		z.SetText(sb.String())
+		for line := range 1000 {
+			z.Tags.Upsert(z.Tags.CloneTag(z.Config.HighlightTag),
+				CharInterval{Start: CharPos{Line: line, Column: 10}, End: CharPos{Line: line, Column: 14}})
+		}
+		z.refreshProc()
+	})
+	return z
+}
+
+func TestRefreshOnlyStaleRows(t *testing.T) {
+	z := newRefreshTestEditor(t)
+	z.Do(func() {
+		z.Config.DrawCaret = false
+		z.refreshProc()
+		z.computeDisplayLines()
+		if stale := z.staleRows(); len(stale) != 0 {
+			t.Errorf("rows %v are stale without a change", stale)
+		}
+
+		// editing a line
+		z.Rows[3] = append([]rune("x"), z.Rows[3]...)
+		z.markChanged()
+		z.computeDisplayLines()
+		if stale := z.staleRows(); !slices.Equal(stale, []int{3}) {
+			t.Errorf("stale rows after editing line 3 = %v, want [3]", stale)
+		}
+
+		// tagging a line
+		z.Tags.Upsert(z.Tags.CloneTag(z.Config.ErrorTag),
+			CharInterval{Start: CharPos{Line: 5, Column: 0}, End: CharPos{Line: 6, Column: 2}})
+		z.computeDisplayLines()
+		if stale := z.staleRows(); !slices.Equal(stale, []int{5, 6}) {
+			t.Errorf("stale rows after tagging lines 5 and 6 = %v, want [5 6]", stale)
+		}
+
+		// scrolling changes all rows
+		z.lineOffset++
+		z.computeDisplayLines()
+		if stale := z.staleRows(); len(stale) != z.Lines {
+			t.Errorf("%d rows are stale after scrolling, want %d", len(stale), z.Lines)
+		}
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	z := newRefreshTestEditor(t)
+	z.Do(func() {
+		z.Config.DrawCaret = false
+		z.refreshProc()
+		want := z.grid.Rows[10].Cells[0].Rune
+		// a row that no longer displays what its text and tags say is only corrected by Invalidate
+		z.grid.Rows[10].Cells[0].Rune = '#'
+		z.refreshProc()
+		if got := z.grid.Rows[10].Cells[0].Rune; got != '#' {
+			t.Fatal("unchanged row 10 was rendered again")
+		}
+		z.Invalidate()
+		if got := z.grid.Rows[10].Cells[0].Rune; got != want {
+			t.Errorf("row 10 starts with %q after Invalidate, want %q", got, want)
+		}
+	})
+}
+
+func TestRefreshKeepsUnchangedRows(t *testing.T) {
+	z := newRefreshTestEditor(t)
+	z.Do(func() {
+		z.Config.DrawCaret = false
+		z.refreshProc()
+		want := slices.Clone(z.grid.Rows[10].Cells)
+		z.Insert([]rune("abc"), CharPos{Line: 3, Column: 0})
+		z.refreshProc()
+		if got := string(cellRunes(z.grid.Rows[3].Cells[:6])); got != "abc3: " {
+			t.Errorf("row 3 starts with %q, want %q", got, "abc3: ")
+		}
+		if !slices.Equal(z.grid.Rows[10].Cells, want) {
+			t.Error("row 10 changed although its line was not edited")
+		}
+	})
+}
+
+// cellRunes returns the runes of the given cells.
+func cellRunes(cells []widget.TextGridCell) []rune {
+	r := make([]rune, len(cells))
+	for i := range cells {
+		r[i] = cells[i].Rune
+	}
+	return r
+}
+
+func BenchmarkRefresh(b *testing.B) {
+	b.Run("full grid", func(b *testing.B) {
+		z := newRefreshTestEditor(b)
+		b.ResetTimer()
+		for range b.N {
+			z.Do(func() {
+				z.shownRows = nil
+				z.refreshProc()
+			})
+		}
+	})
+	b.Run("dirty rows", func(b *testing.B) {
+		z := newRefreshTestEditor(b)
+		b.ResetTimer()
+		for i := range b.N {
+			z.Do(func() {
+				z.Rows[i%z.Lines][0] = rune('a' + i%26)
+				z.refreshProc()
+			})
+		}
+	})
+}
+
+func BenchmarkTyping(b *testing.B) {
+	z := newRefreshTestEditor(b)
+	z.Do(func() { z.SetCaret(CharPos{Line: 20, Column: 0}) })
+	b.ResetTimer()
+	for i := range b.N {
+		if i%60 == 59 {
+			z.Do(z.Return)
+			continue
+		}
+		z.TypedRune(rune('a' + i%26))
+	}
+}
@@ -0,0 +1,159 @@
+package zedit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+)
+
+func TestScrollToEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   int
+		wantTop int
+	}{
+		{"longer than viewport", 20, 15},
+		{"as long as viewport", 5, 0},
+		{"shorter than viewport", 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newTestEditor(t, 80, 5)
+			z.Config.MinRefreshInterval = 0
+			topLine := func() (top int) {
+				z.Do(func() { top = z.TopLine() })
+				return top
+			}
+			z.Do(func() { z.SetText(strings.TrimSuffix(strings.Repeat("line\n", tt.lines), "\n")) })
+			z.Do(func() {
+				for range tt.lines + 10 {
+					z.ScrollDown()
+				}
+			})
+			if top := topLine(); top != tt.wantTop {
+				t.Errorf("after ScrollDown TopLine() = %d, want %d", top, tt.wantTop)
+			}
+			z.Do(func() { z.SetTopLine(0) })
+			z.Scrolled(&fyne.ScrollEvent{Scrolled: fyne.Delta{DY: -1e6}})
+			if top := topLine(); top != tt.wantTop {
+				t.Errorf("after Scrolled TopLine() = %d, want %d", top, tt.wantTop)
+			}
+			z.Scrolled(&fyne.ScrollEvent{Scrolled: fyne.Delta{DY: 1e6}})
+			if top := topLine(); top != 0 {
+				t.Errorf("after scrolling back TopLine() = %d, want 0", top)
+			}
+		})
+	}
+}
+
+func TestScrollRightTracksLongestLine(t *testing.T) {
+	z := newTestEditor(t, 10, 5)
+	check := func(what string, wantLen int) {
+		t.Helper()
+		z.Do(func() {
+			z.ScrollRight(1000)
+			if want := wantLen - z.Columns; z.columnOffset != want {
+				t.Errorf("%s: column offset after scrolling right = %d, want %d", what, z.columnOffset, want)
+			}
+			z.ScrollLeft(1000)
+		})
+	}
+	z.Do(func() {
+		z.Config.LineWrap = false
+		z.SetText("short\n" + strings.Repeat("x", 30) + "\nlines")
+	})
+	check("after SetText", 31)
+
+	// the bounds grow when a line becomes the longest one
+	z.Do(func() { z.Insert([]rune(strings.Repeat("y", 40)), CharPos{Line: 2, Column: 0}) })
+	check("after lengthening a line", 46)
+
+	// the bounds shrink when the longest line is shortened
+	z.Do(func() { z.Delete(CharInterval{Start: CharPos{Line: 2, Column: 0}, End: CharPos{Line: 2, Column: 29}}) })
+	check("after shortening the longest line", 31)
+
+	// the bounds follow a loaded text
+	other := newTestEditor(t, 10, 5)
+	var buf bytes.Buffer
+	other.Do(func() {
+		other.Config.LineWrap = false
+		other.SetText(strings.Repeat("z", 50))
+		if err := other.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	z.Do(func() {
+		if err := z.Load(&buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	check("after Load", 51)
+}
+
+func TestHScrollTracksLongestLine(t *testing.T) {
+	z := newTestEditor(t, 10, 5)
+	check := func(text string, wantVisible bool, wantLen int) {
+		t.Helper()
+		z.Do(func() {
+			z.SetText(text)
+			if got := z.hScroll.Visible(); got != wantVisible {
+				t.Errorf("%q: horizontal scroll bar visible = %v, want %v", text, got, wantVisible)
+			}
+			if !wantVisible {
+				return
+			}
+			want := float32(wantLen-z.Columns)*z.charSize.Width + z.hScroll.Size().Width
+			if got := z.hSpacer.Size().Width; got != want {
+				t.Errorf("%q: spacer width = %v, want %v", text, got, want)
+			}
+		})
+	}
+	z.Do(func() {
+		z.Config.LineWrap = false
+		z.Config.MinRefreshInterval = 0
+	})
+	check("short\nlines", false, 0)
+	check("short\n"+strings.Repeat("x", 30)+"\nlines", true, 31)
+	check(strings.Repeat("x", 20)+"\n"+strings.Repeat("y", 40), true, 41)
+
+	// the bounds shrink when the longest line is shortened
+	z.Do(func() { z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 0}, End: CharPos{Line: 1, Column: 29}}) })
+	z.Do(func() {
+		want := float32(21-z.Columns)*z.charSize.Width + z.hScroll.Size().Width
+		if got := z.hSpacer.Size().Width; got != want {
+			t.Errorf("after shortening the longest line spacer width = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestScrollRightStopsAtLongestLine(t *testing.T) {
+	z := newTestEditor(t, 10, 5)
+	z.Do(func() {
+		z.Config.LineWrap = false
+		z.Config.MinRefreshInterval = 0
+		z.SetText("short\n" + strings.Repeat("x", 40))
+	})
+	runConcurrently(t, func() {
+		z.Do(func() {
+			for range 10 {
+				z.ScrollRight(10)
+			}
+		})
+	})
+	z.Do(func() {
+		if got, want := z.columnOffset, 41-z.Columns; got != want {
+			t.Errorf("column offset = %d after scrolling right past the longest line, want %d", got, want)
+		}
+		if got, want := z.hScroll.Offset.X, float32(z.columnOffset)*z.charSize.Width; got != want {
+			t.Errorf("scroll bar offset = %v, want %v", got, want)
+		}
+		// the caret may move the column offset beyond the scroll range, which is clamped at refresh
+		z.columnOffset = 100
+		z.Refresh()
+		if got, want := z.columnOffset, 41-z.Columns; got != want {
+			t.Errorf("column offset = %d after refresh, want %d", got, want)
+		}
+	})
+}
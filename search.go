@@ -0,0 +1,263 @@
+package zedit
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+)
+
+// FindOptions determine how Find matches the query.
+type FindOptions struct {
+	IgnoreCase bool // match regardless of letter case
+	WholeWord  bool // only match if the match is not preceded or followed by a word rune
+}
+
+// Find returns all matches of the query in the text and marks them with search tags based on
+// Config.SearchTag, replacing the marks of a previous search. Matches are found in the logical text
+// of each paragraph, so a match may span a soft line break but not a hard one. The query and options
+// are remembered for FindNext and FindPrev.
+func (z *Editor) Find(query string, opts FindOptions) []CharInterval {
+	z.ClearSearch()
+	z.searchQuery = query
+	z.searchOptions = opts
+	matches := z.findMatches([]rune(query), opts)
+	for _, match := range matches {
+		z.Tags.Upsert(z.Tags.CloneTag(z.Config.SearchTag), match)
+	}
+	z.Refresh()
+	return matches
+}
+
+// ClearSearch removes the marks of the last search.
+func (z *Editor) ClearSearch() {
+	z.Tags.DeleteByName(z.Config.SearchTag.Name())
+	z.Refresh()
+}
+
+// FindNext selects the next match of the last search after the caret and puts the caret at its
+// start, wrapping around at the end of the text. It returns false if there is no match.
+func (z *Editor) FindNext() bool {
+	matches := z.findMatches([]rune(z.searchQuery), z.searchOptions)
+	if len(matches) == 0 {
+		return false
+	}
+	match := matches[0]
+	for _, m := range matches {
+		if CmpPos(m.Start, z.caretPos) > 0 {
+			match = m
+			break
+		}
+	}
+	z.selectMatch(match)
+	return true
+}
+
+// FindPrev selects the previous match of the last search before the caret and puts the caret at its
+// start, wrapping around at the start of the text. It returns false if there is no match.
+func (z *Editor) FindPrev() bool {
+	matches := z.findMatches([]rune(z.searchQuery), z.searchOptions)
+	if len(matches) == 0 {
+		return false
+	}
+	match := matches[len(matches)-1]
+	for i := len(matches) - 1; i >= 0; i-- {
+		if CmpPos(matches[i].Start, z.caretPos) < 0 {
+			match = matches[i]
+			break
+		}
+	}
+	z.selectMatch(match)
+	return true
+}
+
+// selectMatch selects the match, puts the caret at its start, and scrolls to it.
+func (z *Editor) selectMatch(match CharInterval) {
+	z.SetCaret(match.Start)
+	z.Select(match)
+	z.scrollToCaret()
+}
+
+// findMatches returns the intervals of all matches of the query in document order.
+func (z *Editor) findMatches(query []rune, opts FindOptions) []CharInterval {
+	var matches []CharInterval
+	if len(query) == 0 {
+		return matches
+	}
+	if opts.IgnoreCase {
+		query = foldRunes(query)
+	}
+	for row := 0; row <= z.LastLine(); row++ {
+		end := z.FindParagraphEnd(row, z.Config.HardLF)
+		text := []rune(z.paragraphString(row, end))
+		if opts.IgnoreCase {
+			text = foldRunes(text)
+		}
+		for i := 0; i+len(query) <= len(text); i++ {
+			if !slices.Equal(text[i:i+len(query)], query) {
+				continue
+			}
+			if opts.WholeWord && ((i > 0 && IsWordRune(text[i-1])) ||
+				(i+len(query) < len(text) && IsWordRune(text[i+len(query)]))) {
+				continue
+			}
+			matches = append(matches, CharInterval{Start: z.paragraphOffsetToPos(row, i),
+				End: z.paragraphOffsetToPos(row, i+len(query)-1)})
+			i += len(query) - 1
+		}
+		row = end
+	}
+	return matches
+}
+
+// foldRunes returns a lower case copy of the runes.
+func foldRunes(r []rune) []rune {
+	folded := make([]rune, len(r))
+	for i := range r {
+		folded[i] = unicode.ToLower(r[i])
+	}
+	return folded
+}
+
+// FindRegexp returns the intervals of all non-empty matches of the regular expression in the logical
+// text, in which soft line breaks are removed and paragraphs are separated by newlines.
+func (z *Editor) FindRegexp(re *regexp.Regexp) []CharInterval {
+	text, positions := z.logicalText()
+	conv := logicalRange{text: text, positions: positions}
+	var matches []CharInterval
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		if iv, ok := conv.interval(loc[0], loc[1]); ok {
+			matches = append(matches, iv)
+		}
+	}
+	return matches
+}
+
+// ReplaceAll replaces all non-empty matches of the regular expression in the logical text by the
+// replacement, in which $1 or ${name} refer to submatches as in regexp.Regexp.Expand. The text is
+// reflown and tags are adjusted as for Delete and Insert. Matches that intersect a protected region
+// are not replaced. It returns the number of replacements.
+func (z *Editor) ReplaceAll(re *regexp.Regexp, replacement string) int {
+	text, positions := z.logicalText()
+	locs := re.FindAllStringSubmatchIndex(text, -1)
+	type replace struct {
+		interval CharInterval
+		s        string
+	}
+	conv := logicalRange{text: text, positions: positions}
+	var replaces []replace
+	for _, loc := range locs {
+		if iv, ok := conv.interval(loc[0], loc[1]); ok && !z.isProtectedRange(iv) {
+			replaces = append(replaces, replace{iv, string(re.ExpandString(nil, replacement, text, loc))})
+		}
+	}
+	if len(replaces) == 0 ||
+		!z.confirmBulkEdit(CharInterval{Start: replaces[0].interval.Start, End: replaces[len(replaces)-1].interval.End}) {
+		return 0
+	}
+	caret := z.CreateAnchor(z.caretPos)
+	defer caret.Release()
+	for i := len(replaces) - 1; i >= 0; i-- {
+		z.Delete(replaces[i].interval)
+		z.SetCaret(replaces[i].interval.Start)
+		z.insertText(replaces[i].s)
+	}
+	if pos, ok := caret.Pos(); ok {
+		z.SetCaret(pos)
+	}
+	z.Refresh()
+	return len(replaces)
+}
+
+// ReplaceInSelection replaces all matches of the query that lie entirely within the current
+// selection by the replacement and returns the number of replacements. Matches are found as for
+// Find. Afterwards, the selection covers the same text as before, including the replacements. The
+// rest of the text is not changed. Matches that intersect a protected region are not replaced. If
+// there is no selection, nothing is replaced.
+func (z *Editor) ReplaceInSelection(query, replacement string, opts FindOptions) int {
+	sel, ok := z.CurrentSelection()
+	if !ok {
+		return 0
+	}
+	var matches []CharInterval
+	for _, match := range z.findMatches([]rune(query), opts) {
+		if containsInterval(sel, match) && !z.isProtectedRange(match) {
+			matches = append(matches, match)
+		}
+	}
+	if len(matches) == 0 || !z.confirmBulkEdit(CharInterval{Start: matches[0].Start, End: matches[len(matches)-1].End}) {
+		return 0
+	}
+	// The selection is restored from logical offsets, since reflowing may move its positions. All
+	// replacements are after the start, so only the end changes, by the difference in length.
+	_, positions := z.logicalText()
+	start := sort.Search(len(positions), func(i int) bool { return CmpPos(positions[i], sel.Start) >= 0 })
+	end := sort.Search(len(positions), func(i int) bool { return CmpPos(positions[i], sel.End) > 0 }) - 1
+	caretAtStart := CmpPos(z.caretPos, sel.Start) <= 0
+	end += len(matches) * (utf8.RuneCountInString(replacement) - utf8.RuneCountInString(query))
+	for i := len(matches) - 1; i >= 0; i-- {
+		z.Delete(matches[i])
+		z.SetCaret(matches[i].Start)
+		z.insertText(replacement)
+	}
+	_, positions = z.logicalText()
+	if start >= len(positions) || end < start {
+		// the selection has become empty
+		z.SetCaret(sel.Start)
+		z.Refresh()
+		return len(matches)
+	}
+	interval := CharInterval{Start: positions[start], End: positions[min(end, len(positions)-1)]}
+	if caretAtStart {
+		z.SetCaret(interval.Start)
+	} else {
+		z.SetCaret(z.advancePos(interval.End, 1))
+	}
+	z.Select(interval)
+	return len(matches)
+}
+
+// logicalText returns the text without soft line feeds and with hard line feeds as newlines, and the
+// position of each of its runes. The final line feed is not included.
+func (z *Editor) logicalText() (string, []CharPos) {
+	var sb strings.Builder
+	var positions []CharPos
+	for i := range z.Rows {
+		row := z.row(i)
+		for j, c := range row {
+			if j == len(row)-1 {
+				if c == z.Config.SoftLF || i == z.LastLine() {
+					continue
+				}
+				c = '\n'
+			}
+			sb.WriteRune(c)
+			positions = append(positions, CharPos{Line: i, Column: j})
+		}
+	}
+	return sb.String(), positions
+}
+
+// logicalRange converts byte offsets in the logical text to char intervals. Offsets must be converted
+// in ascending order, since runes are counted from the previous offset.
+type logicalRange struct {
+	text      string
+	positions []CharPos
+	offset    int // byte offset of the last conversion
+	runes     int // rune index of offset
+}
+
+// interval converts the byte offsets start and end (exclusive) to a char interval, and returns false if
+// the range is empty.
+func (l *logicalRange) interval(start, end int) (CharInterval, bool) {
+	if start >= end {
+		return CharInterval{}, false
+	}
+	from := l.runes + utf8.RuneCountInString(l.text[l.offset:start])
+	to := from + utf8.RuneCountInString(l.text[start:end])
+	l.offset, l.runes = end, to
+	return CharInterval{Start: l.positions[from], End: l.positions[to-1]}, true
+}
@@ -0,0 +1,80 @@
+package zedit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// SimulateTyping types the given string as if it was entered by the user, rune by rune. A newline
+// is typed as the return key. This is intended for tests and must not be called from within Do.
+func (z *Editor) SimulateTyping(s string) {
+	for _, r := range s {
+		if r == '\n' {
+			z.SimulateKey(fyne.KeyReturn)
+			continue
+		}
+		z.TypedRune(r)
+	}
+}
+
+// SimulateKey presses the key with the given name as if it was pressed by the user. This is intended
+// for tests and must not be called from within Do.
+func (z *Editor) SimulateKey(name fyne.KeyName) {
+	z.TypedKey(&fyne.KeyEvent{Name: name})
+}
+
+// DumpState returns a deterministic textual description of the rows, caret, selection, and tags of
+// the editor, which is intended for comparing the state of the editor in tests. Each row is written
+// in quotes followed by its line ending, which is "hard" or "soft". Positions are written as
+// line:column and tags are sorted by their interval, name, and index.
+func (z *Editor) DumpState() string {
+	var sb strings.Builder
+	sb.WriteString("rows:\n")
+	for i := range z.Rows {
+		row := z.row(i)
+		text, lf := row, "none"
+		if k := len(row); k > 0 {
+			switch row[k-1] {
+			case z.Config.HardLF:
+				text, lf = row[:k-1], "hard"
+			case z.Config.SoftLF:
+				text, lf = row[:k-1], "soft"
+			}
+		}
+		fmt.Fprintf(&sb, "%d: %q %s\n", i, string(text), lf)
+	}
+	fmt.Fprintf(&sb, "caret: %s\n", dumpPos(z.caretPos))
+	if sel, ok := z.CurrentSelection(); ok {
+		fmt.Fprintf(&sb, "selection: %s-%s\n", dumpPos(sel.Start), dumpPos(sel.End))
+	} else {
+		sb.WriteString("selection: none\n")
+	}
+	tags := z.Tags.AllTags()
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := tags[i], tags[j]
+		if c := CmpPos(a.Interval.Start, b.Interval.Start); c != 0 {
+			return c < 0
+		}
+		if c := CmpPos(a.Interval.End, b.Interval.End); c != 0 {
+			return c < 0
+		}
+		if a.Tag.Name() != b.Tag.Name() {
+			return a.Tag.Name() < b.Tag.Name()
+		}
+		return a.Tag.Index() < b.Tag.Index()
+	})
+	sb.WriteString("tags:\n")
+	for _, tag := range tags {
+		fmt.Fprintf(&sb, "%s#%d: %s-%s\n", tag.Tag.Name(), tag.Tag.Index(), dumpPos(tag.Interval.Start),
+			dumpPos(tag.Interval.End))
+	}
+	return sb.String()
+}
+
+// dumpPos returns the position as line:column.
+func dumpPos(pos CharPos) string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
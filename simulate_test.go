@@ -0,0 +1,72 @@
+package zedit
+
+import (
+	"fmt"
+	"testing"
+
+	"fyne.io/fyne/v2"
+)
+
+func TestSimulateTyping(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() { z.SetText("") })
+	z.SimulateTyping("ab\ncd")
+	var got string
+	z.Do(func() { got = z.DumpState() })
+	want := `rows:
+0: "ab" hard
+1: "cd" hard
+caret: 1:2
+selection: none
+tags:
+`
+	if got != want {
+		t.Errorf("state after typing:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSimulateKey(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() { z.SetText("") })
+	z.SimulateTyping("hello")
+	z.SimulateKey(fyne.KeyLeft)
+	z.SimulateKey(fyne.KeyLeft)
+	z.SimulateKey(fyne.KeyBackspace)
+	z.SimulateKey(fyne.KeyReturn)
+	var got string
+	z.Do(func() {
+		z.Select(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 0, Column: 1}})
+		got = z.DumpState()
+	})
+	want := fmt.Sprintf(`rows:
+0: "he" hard
+1: "lo" hard
+caret: 1:0
+selection: 0:0-0:1
+tags:
+selection#%d: 0:0-0:1
+`, z.Config.SelectionTag.Index())
+	if got != want {
+		t.Errorf("state after typing and pressing keys:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDumpStateSoftWrap(t *testing.T) {
+	z := newTestEditor(t, 5, 10)
+	var got string
+	z.Do(func() {
+		z.SetText("abc defg")
+		z.SetCaret(CharPos{Line: 1, Column: 2})
+		got = z.DumpState()
+	})
+	want := `rows:
+0: "abc " soft
+1: "defg" hard
+caret: 1:2
+selection: none
+tags:
+`
+	if got != want {
+		t.Errorf("state of wrapped text:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -17,6 +17,9 @@ type TagEvent int
 const (
 	CaretEnterEvent TagEvent = iota
 	CaretLeaveEvent
+	MouseHoverEvent   // the mouse pointer rests on the tag's interval
+	TagClickEvent     // the tag's interval was clicked
+	TagCtrlClickEvent // the tag's interval was clicked while holding the control key
 )
 
 type TagFunc func(evt TagEvent, tag Tag, interval CharInterval)
@@ -377,6 +380,84 @@ func (t *TagContainer) TagsByName(name string) (*orderedset.OrderedSet[Tag], boo
 	return tags, ok
 }
 
+// TagsByNameSorted returns all tags with the given name that are associated with an interval,
+// sorted by the start position of their intervals.
+func (t *TagContainer) TagsByNameSorted(name string) []TagWithInterval {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	result := make([]TagWithInterval, 0)
+	tags, ok := t.names[name]
+	if !ok || tags == nil {
+		return result
+	}
+	loop := tags.Iter()
+	for {
+		tag, ok := loop.Next()
+		if !ok {
+			break
+		}
+		if interval, ok := t.tags[tag]; ok {
+			result = append(result, TagWithInterval{Tag: tag, Interval: interval})
+		}
+	}
+	slices.SortStableFunc(result, func(a, b TagWithInterval) int {
+		if c := CmpPos(a.Interval.Start, b.Interval.Start); c != 0 {
+			return c
+		}
+		return CmpPos(a.Interval.End, b.Interval.End)
+	})
+	return result
+}
+
+// TagsInRange returns all tags whose intervals intersect the given interval together with their
+// intervals, sorted by the start position of their intervals. The result is consistent even if tags
+// are changed concurrently.
+func (t *TagContainer) TagsInRange(interval CharInterval) []TagWithInterval {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	result := make([]TagWithInterval, 0)
+	tags, ok := t.lookup.AllIntersections(interval.Start, interval.End)
+	if !ok {
+		return result
+	}
+	for _, tag := range tags {
+		if tag == nil {
+			continue
+		}
+		if iv, ok := t.tags[tag]; ok {
+			result = append(result, TagWithInterval{Tag: tag, Interval: iv})
+		}
+	}
+	slices.SortStableFunc(result, func(a, b TagWithInterval) int {
+		if c := CmpPos(a.Interval.Start, b.Interval.Start); c != 0 {
+			return c
+		}
+		return CmpPos(a.Interval.End, b.Interval.End)
+	})
+	return result
+}
+
+// NextTag returns the first tag with the given name whose interval starts after pos.
+func (t *TagContainer) NextTag(name string, pos CharPos) (TagWithInterval, bool) {
+	for _, tag := range t.TagsByNameSorted(name) {
+		if CmpPos(tag.Interval.Start, pos) > 0 {
+			return tag, true
+		}
+	}
+	return TagWithInterval{}, false
+}
+
+// PrevTag returns the last tag with the given name whose interval starts before pos.
+func (t *TagContainer) PrevTag(name string, pos CharPos) (TagWithInterval, bool) {
+	tags := t.TagsByNameSorted(name)
+	for i := len(tags) - 1; i >= 0; i-- {
+		if CmpPos(tags[i].Interval.Start, pos) < 0 {
+			return tags[i], true
+		}
+	}
+	return TagWithInterval{}, false
+}
+
 // CloneTag clones the given tag with a new index, and registers the tag in the container but without an
 // associated interval. If there is no tag in the container, it registers the tag and returns it without cloning it.
 func (t *TagContainer) CloneTag(tag Tag) Tag {
@@ -411,6 +492,7 @@ func (t *TagContainer) CloneTag(tag Tag) Tag {
 // StyleContainer holds a number of tag stylers. The data structure is threadsafe.
 type StyleContainer struct {
 	stylers []TagStyler
+	gen     uint64
 	mutex   sync.Mutex
 }
 
@@ -422,6 +504,7 @@ func NewStyleContainer() *StyleContainer {
 func (c *StyleContainer) AddStyler(styler TagStyler) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.gen++
 	if c.stylers == nil {
 		c.stylers = make([]TagStyler, 1)
 		c.stylers[0] = styler
@@ -450,6 +533,7 @@ func (c *StyleContainer) HasStyler(tagName string) bool {
 func (c *StyleContainer) RemoveStyler(tag Tag) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.gen++
 	if c.stylers == nil {
 		return
 	}
@@ -458,6 +542,13 @@ func (c *StyleContainer) RemoveStyler(tag Tag) {
 	})
 }
 
+// generation returns a number that changes whenever a styler is added or removed.
+func (c *StyleContainer) generation() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.gen
+}
+
 // Stylers returns all tag stylers.
 func (c *StyleContainer) Stylers() []TagStyler {
 	c.mutex.Lock()
@@ -0,0 +1,32 @@
+package zedit
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTagsAtCaret(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.Do(func() {
+		z.SetText("abc def ghi")
+		iv := func(start, end int) CharInterval {
+			return CharInterval{Start: CharPos{Line: 0, Column: start}, End: CharPos{Line: 0, Column: end}}
+		}
+		z.Tags.Add(iv(4, 6), NewTag("word"))
+		z.Tags.Add(iv(0, 10), NewTag("line"))
+		z.Tags.Add(iv(2, 5), NewTag("span"))
+		z.Tags.Add(iv(8, 10), NewTag("other"))
+		z.SetCaret(CharPos{Line: 0, Column: 5})
+		got := z.TagsAtCaret()
+		var names []string
+		for _, tag := range got {
+			names = append(names, tag.Tag.Name())
+		}
+		if want := []string{"line", "span", "word"}; !slices.Equal(names, want) {
+			t.Errorf("TagsAtCaret() returned %v, want %v", names, want)
+		}
+		if want := z.TagsAt(z.caretPos); !slices.Equal(got, want) {
+			t.Errorf("TagsAtCaret() = %v, want TagsAt(caret) = %v", got, want)
+		}
+	})
+}
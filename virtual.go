@@ -0,0 +1,145 @@
+package zedit
+
+import (
+	"golang.org/x/exp/slices"
+)
+
+// virtualLine is a line of text that is displayed below a text line but is not part of the text.
+// Its position is tracked by a zero-length tag at the end of the line it belongs to, so it moves
+// along with that line when the text is edited.
+type virtualLine struct {
+	tag   Tag
+	text  []rune
+	style Style
+}
+
+// AddVirtualLine adds a line of text that is displayed below the given line with the given style.
+// Virtual lines are not part of the text, cannot be edited, and are not returned by GetText. They
+// move with the line they belong to when lines above them are inserted or deleted, and they are
+// removed when that line is deleted. Multiple virtual lines after the same line are displayed in the
+// order in which they were added. This is useful for displaying inline diagnostics, for example.
+func (z *Editor) AddVirtualLine(afterLine int, text string, style Style) {
+	if afterLine < 0 || afterLine > z.LastLine() {
+		return
+	}
+	tag := z.Tags.CloneTag(NewTag("_virtual"))
+	pos := CharPos{Line: afterLine, Column: z.LastColumn(afterLine)}
+	z.Tags.Upsert(tag, CharInterval{Start: pos, End: pos})
+	z.virtualLines = append(z.virtualLines, &virtualLine{tag: tag, text: []rune(text), style: style})
+	z.Refresh()
+}
+
+// ClearVirtualLines removes all virtual lines from the editor.
+func (z *Editor) ClearVirtualLines() {
+	for _, vl := range z.virtualLines {
+		z.Tags.Delete(vl.tag)
+	}
+	z.virtualLines = nil
+	z.Refresh()
+}
+
+// virtualLinesAfter returns the virtual lines displayed below the given line, removing virtual lines
+// whose tag is gone because the line they belonged to has been deleted.
+func (z *Editor) virtualLinesAfter(line int) []*virtualLine {
+	var result []*virtualLine
+	z.virtualLines = slices.DeleteFunc(z.virtualLines, func(vl *virtualLine) bool {
+		interval, ok := z.Tags.Lookup(vl.tag)
+		if !ok {
+			return true
+		}
+		if interval.Start.Line == line {
+			result = append(result, vl)
+		}
+		return false
+	})
+	return result
+}
+
+// virtualLineCounts returns the number of virtual lines displayed below each text line that has any.
+func (z *Editor) virtualLineCounts() map[int]int {
+	counts := make(map[int]int)
+	for _, vl := range z.virtualLines {
+		if interval, ok := z.Tags.Lookup(vl.tag); ok {
+			counts[interval.Start.Line]++
+		}
+	}
+	return counts
+}
+
+// computeDisplayLines computes the mapping from grid rows to text lines and virtual lines, skipping
+// lines hidden in folded regions. Grid row i displays the text line z.displayLines[i] if
+// z.displayVirtual[i] is nil, and the virtual line z.displayVirtual[i] otherwise, in which case
+// z.displayLines[i] is -1.
+func (z *Editor) computeDisplayLines() {
+	if len(z.displayLines) != z.Lines {
+		z.displayLines = make([]int, z.Lines)
+		z.displayVirtual = make([]*virtualLine, z.Lines)
+	}
+	line := z.lineOffset
+	for i := 0; i < z.Lines; {
+		z.displayLines[i] = line
+		z.displayVirtual[i] = nil
+		i++
+		if len(z.virtualLines) > 0 && line <= z.LastLine() {
+			for _, vl := range z.virtualLinesAfter(line) {
+				if i >= z.Lines {
+					break
+				}
+				z.displayLines[i] = -1
+				z.displayVirtual[i] = vl
+				i++
+			}
+		}
+		if end, ok := z.foldedEnd(line); ok {
+			line = end
+		}
+		line++
+	}
+}
+
+// scrollDownToDisplay scrolls down by as few lines as needed to display the given line below the top
+// line, taking into account the rows taken by virtual lines and the lines hidden by folded regions.
+func (z *Editor) scrollDownToDisplay(line int) {
+	top := z.lineOffset
+	for top < line {
+		top++
+		z.lineOffset = top
+		z.computeDisplayLines()
+		if _, ok := z.gridRowOf(line); ok {
+			break
+		}
+	}
+	z.SetTopLine(top)
+}
+
+// gridRowOf returns the grid row in which the given text line is displayed, and false if the line is
+// not displayed.
+func (z *Editor) gridRowOf(line int) (int, bool) {
+	if len(z.displayLines) != z.Lines {
+		row := line - z.lineOffset
+		return row, row >= 0 && row < z.Lines
+	}
+	for i, n := range z.displayLines {
+		if n == line {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// lineAtGridRow returns the text line displayed in the given grid row. If the row displays a virtual
+// line, the text line to which the virtual line belongs is returned.
+func (z *Editor) lineAtGridRow(row int) int {
+	if len(z.displayLines) != z.Lines || row < 0 {
+		return z.lineOffset + row
+	}
+	if row >= z.Lines {
+		return z.lineAtGridRow(z.Lines-1) + row - z.Lines + 1
+	}
+	for i := row; i >= 0; i-- {
+		if z.displayLines[i] >= 0 {
+			return z.displayLines[i]
+		}
+	}
+	return z.lineOffset
+}
@@ -0,0 +1,53 @@
+package zedit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaretDownScrollsPastVirtualLines(t *testing.T) {
+	z := newTestEditor(t, 80, 5)
+	z.Config.MinRefreshInterval = 0
+	z.Do(func() {
+		z.SetText(strings.Repeat("line\n", 19) + "line")
+		z.AddVirtualLine(1, "first note", Style{})
+		z.AddVirtualLine(1, "second note", Style{})
+		z.AddVirtualLine(6, "third note", Style{})
+	})
+	for i := 1; i <= 10; i++ {
+		z.Do(func() {
+			z.MoveCaret(CaretDown)
+			z.computeDisplayLines()
+			if z.caretPos.Line != i {
+				t.Fatalf("caret line = %d, want %d", z.caretPos.Line, i)
+			}
+			if _, ok := z.gridRowOf(i); !ok {
+				t.Errorf("caret line %d is not displayed with top line %d", i, z.lineOffset)
+			}
+		})
+	}
+}
+
+func TestScrollRangeIncludesVirtualLines(t *testing.T) {
+	z := newTestEditor(t, 80, 5)
+	z.Config.MinRefreshInterval = 0
+	z.Do(func() {
+		z.SetText(strings.Repeat("line\n", 19) + "line")
+		z.AddVirtualLine(19, "first note", Style{})
+		z.AddVirtualLine(19, "second note", Style{})
+		for i := 0; i < 30; i++ {
+			z.ScrollDown()
+		}
+		z.computeDisplayLines()
+		if z.lineOffset != 17 {
+			t.Errorf("line offset = %d, want 17", z.lineOffset)
+		}
+		if z.displayLines[2] != 19 || z.displayVirtual[4] == nil {
+			t.Errorf("last line and its virtual lines are not displayed in the bottom rows: %v", z.displayLines)
+		}
+		want := float32(z.lineOffset+z.Lines) * z.RowHeight()
+		if got := z.vSpacer.Size().Height; got != want {
+			t.Errorf("spacer height = %v, want %v", got, want)
+		}
+	})
+}
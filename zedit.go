@@ -3,6 +3,7 @@ package zedit
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"image/color"
@@ -15,6 +16,7 @@ import (
 	"sync/atomic"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -25,12 +27,12 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/chewxy/math32"
 	"github.com/dimchansky/utfbom"
-	"github.com/lucasb-eyer/go-colorful"
 	"golang.org/x/exp/slices"
+	"golang.org/x/text/unicode/norm"
 )
 
 const MAGIC = 86637303 // magic cookie
-const VERSION = 100    // this version 100 == "v1.0.0"
+const VERSION = 101    // this version 101 == "v1.0.1"
 const MINVERSION = 100 // minimum required version
 
 var ErrInvalidStream = fmt.Errorf("invalid input text format")
@@ -38,6 +40,8 @@ var ErrVersionTooLow = fmt.Errorf("this software's version for input text readin
 var ErrTooManyLines = fmt.Errorf("too many lines, the input text could not be read because it is too large")
 var ErrTooLongLine = fmt.Errorf("a line in the input text was too large")
 var ErrTooManyTags = fmt.Errorf("the input text has too many tags")
+var ErrPosOutOfRange = fmt.Errorf("the position is not within the text")
+var ErrModifiedConflict = fmt.Errorf("the file was changed externally but the text has been modified in the editor")
 
 type CaretMovement int
 
@@ -54,6 +58,37 @@ const (
 	CaretHalfPageUp
 	CaretPageDown
 	CaretPageUp
+	CaretParagraphStart
+	CaretParagraphEnd
+	CaretWordLeft
+	CaretWordRight
+)
+
+// NormalizationForm is a Unicode normalization form applied to text entering the editor.
+type NormalizationForm int
+
+const (
+	NormalizeNone NormalizationForm = iota // text is not normalized
+	NormalizeNFC                           // canonical composition
+	NormalizeNFD                           // canonical decomposition
+)
+
+// ControlCharPolicy determines how control characters other than tabs and line feeds are handled.
+type ControlCharPolicy int
+
+const (
+	ControlKeep    ControlCharPolicy = iota // control characters are kept and displayed as they are
+	ControlStrip                            // control characters are removed from text set or inserted
+	ControlPicture                          // control characters are kept but displayed as control picture glyphs like ␌
+)
+
+// CaretShape determines how the caret is drawn.
+type CaretShape int
+
+const (
+	CaretBlock     CaretShape = iota // the caret cell is drawn inverted
+	CaretBar                         // a thin vertical bar is drawn at the left edge of the caret cell
+	CaretUnderline                   // a thin line is drawn at the bottom of the caret cell
 )
 
 type EditorEvent int
@@ -63,58 +98,128 @@ const (
 	WordChangeEvent
 	SelectWordEvent
 	OnChangeEvent
+	TagHoverEvent        // the tags under the resting mouse pointer have changed, see HoveredTags
+	SelectionChangeEvent // the selection has been set, changed, or removed, see CurrentSelection
+	ScrollEvent          // the top line or the column offset has changed, see TopLine
+	GutterClickEvent     // a line number in the gutter has been clicked, see GutterClickLine
 )
 
 type EventHandler func(evt EditorEvent, editor *Editor) // used for editor events
 
+// Change describes the last change of the text, as returned by LastChange during an OnChangeEvent.
+// Interval contains the inserted text after the change. If no text was inserted, its start and end
+// are the position at which text was removed. Inserted and Removed are the numbers of runes inserted
+// and removed, where line feeds between paragraphs count as one rune and soft line feeds are not
+// counted.
+type Change struct {
+	Interval CharInterval
+	Inserted int
+	Removed  int
+}
+
 type TagPreWriteFunc func(tag TagWithInterval) error // used before a tag is written
 type TagPostReadFunc func(tag TagWithInterval) error // used after a tag has been read
 type CustomSaveFunc func(enc *json.Encoder) error    // used for writing custom data during Save()
 type CustomLoadFunc func(dec *json.Decoder) error    // used for reading custom data during Load()
+type LineBreakFunc func(prev, next rune) bool        // used for deciding whether word wrap may break between two runes
+type ViewportFunc func(viewport CharInterval)        // used for reporting changes of the visible char interval
+type PosPredicate func(pos CharPos) bool             // used for deciding something about a char position
+type BulkEditFunc func(iv CharInterval, n int) bool  // used for confirming bulk edits
+
+// LineNumberFunc is used for formatting line numbers, it receives the line number and the line number
+// of the caret (for example, to display relative line numbers).
+type LineNumberFunc func(lineNumber, caretLine int) string
+
+// MovementFunc is used for custom caret movements, it receives the current caret position and
+// returns the new one.
+type MovementFunc func(z *Editor, cur CharPos) CharPos
+
+// RuleStyleFunc is used for styling displayed chars by rule, it returns the style for the given rune
+// at the given position and true, or false if the rule does not apply.
+type RuleStyleFunc func(pos CharPos, r rune) (Style, bool)
+
+// ruleStyler is a named rule style function.
+type ruleStyler struct {
+	name string
+	rule RuleStyleFunc
+}
 
 // Config stores configuration information for an editor.
 type Config struct {
-	SelectionTag         Tag             // the tag used for marking selection ranges
-	SelectionStyler      TagStyler       // style of the selection tag
-	HighlightTag         Tag             // for transient highlighting (usually has a different style than selection)
-	HighlightStyler      TagStyler       // style func for highlight
-	MarkTag              Tag             // template for the mark tags
-	MarkTags             []Tag           // a number of pre-configured tags used for marking text (default: 0..9 tags)
-	MarkStyler           TagStyler       // mark style func, using the tag index to distinguish marks
-	ErrorTag             Tag             // for errors
-	ParenErrorTag        Tag             // for wrong right parenthesis
-	ErrorStyler          TagStyler       // style of errors (default: theme error color)
-	ShowLineNumbers      bool            // switches on or off the line number display, which is in a separate grid
-	ShowWhitespace       bool            // show special glyphs for line endings (currently defunct)
-	BlendFG              BlendMode       // how layers of color are blended/composited for text foreground
-	BlendFGSwitched      bool            // whether to switch the colors while blending forground (sometimes makes a difference)
-	BlendBG              BlendMode       // how layers of color are blended for background
-	BlendBGSwitched      bool            // whether the colors are switched while blending background colors (sometimes makes a difference)
-	HardLF               rune            // hard line feed character
-	SoftLF               rune            // soft line feed character (subject to word-wrapping and deletion in text)
-	ScrollFactor         float32         // speed of scrolling
-	TabWidth             int             // If set to 0 the fyne.DefaultTabWidth is used
-	MinRefreshInterval   time.Duration   // minimum interval in ms to refresh display
-	CharDrift            float32         // default 0.4, added to calculation per char when finding char position from x-position
-	LineWrap             bool            // automatically wrap lines (default: true)
-	SoftWrap             bool            // soft wrap lines, if not true wrapping inserst hard line feeds (default: true)
-	HighlightParens      bool            // highlight parentheses and quotation marks (default: true)
-	HighlightParenRange  bool            // highlight the whole range between matching parens (default: false)
-	DrawCaret            bool            // if true, the caret is drawn, if false, the caret is handled but not drawn
-	CaretBlinkDelay      time.Duration   // period after last interaction before caret starts blinking
-	CaretOnDuration      time.Duration   // how long the caret is shown when blinking
-	CaretOffDuration     time.Duration   // how long a blinking caret is off
-	ParagraphLineNumbers bool            // line numbers are based on paragraphs to take into account soft wrap
-	TagPreWrite          TagPreWriteFunc // called before a tag is written
-	TagPostRead          TagPostReadFunc // called after a tag has been read, may be used to re-store callback
-	CustomLoader         CustomLoadFunc  // called during Load after the editor has loaded everything else
-	CustomSaver          CustomSaveFunc  // called after during Save everything else has been saved
-	MaxLines             int64           // maximum number of lines (if 0 or below, no limit) only used during Load
-	MaxColumns           int64           // maximum column length (if 0 or below, no limit) only used during Load
-	MaxTags              int64           // maximum number of tags (if 0 or below, no limit) only used during Load
-	MaxPrintLines        int             // maximum number of lines for printing for console mode, preceding lines are cut off
-	GetWordAtLeft        bool            // if true, word-change event triggers any word left of the caret if the caret is not on a word
-	LiberalGetWordAt     bool            // if true, word boundaries include punctuation but not parentheses (may be useful for Lisp symbol lookup)
+	SelectionTag                 Tag               // the tag used for marking selection ranges
+	SelectionStyler              TagStyler         // style of the selection tag
+	HighlightTag                 Tag               // for transient highlighting (usually has a different style than selection)
+	HighlightStyler              TagStyler         // style func for highlight
+	MarkTag                      Tag               // template for the mark tags
+	MarkTags                     []Tag             // a number of pre-configured tags used for marking text (default: 0..9 tags)
+	MarkStyler                   TagStyler         // mark style func, using the tag index to distinguish marks
+	ErrorTag                     Tag               // for errors
+	ParenErrorTag                Tag               // for wrong right parenthesis
+	ErrorStyler                  TagStyler         // style of errors (default: theme error color)
+	ShowLineNumbers              bool              // switches on or off the line number display, which is in a separate grid
+	ShowWhitespace               bool              // show special glyphs for line endings (currently defunct)
+	BlendFG                      BlendMode         // how layers of color are blended/composited for text foreground
+	BlendFGSwitched              bool              // whether to switch the colors while blending forground (sometimes makes a difference)
+	BlendBG                      BlendMode         // how layers of color are blended for background
+	BlendBGSwitched              bool              // whether the colors are switched while blending background colors (sometimes makes a difference)
+	HardLF                       rune              // hard line feed character
+	SoftLF                       rune              // soft line feed character (subject to word-wrapping and deletion in text)
+	ScrollFactor                 float32           // speed of scrolling
+	TabWidth                     int               // the width of a tab in columns, if 0 or below the default of 4 is used
+	MinRefreshInterval           time.Duration     // minimum interval in ms to refresh display
+	CharDrift                    float32           // deprecated and no longer used, chars are located by the grid's cell width
+	LineWrap                     bool              // automatically wrap lines (default: true)
+	SoftWrap                     bool              // soft wrap lines, if not true wrapping inserst hard line feeds (default: true)
+	HighlightParens              bool              // highlight parentheses and quotation marks (default: true)
+	HighlightParenRange          bool              // highlight the whole range between matching parens (default: false)
+	DrawCaret                    bool              // if true, the caret is drawn, if false, the caret is handled but not drawn
+	CaretBlinkDelay              time.Duration     // period after last interaction before caret starts blinking
+	CaretOnDuration              time.Duration     // how long the caret is shown when blinking
+	CaretOffDuration             time.Duration     // how long a blinking caret is off
+	CaretBlink                   bool              // if false, the caret is drawn steadily and never blinks
+	CaretBlinkRate               time.Duration     // if > 0, sets both the on and off duration of a blinking caret
+	CaretShape                   CaretShape        // how the caret is drawn (default: CaretBlock)
+	ParagraphLineNumbers         bool              // line numbers are based on paragraphs to take into account soft wrap
+	TagPreWrite                  TagPreWriteFunc   // called before a tag is written
+	TagPostRead                  TagPostReadFunc   // called after a tag has been read, may be used to re-store callback
+	CustomLoader                 CustomLoadFunc    // called during Load after the editor has loaded everything else
+	CustomSaver                  CustomSaveFunc    // called after during Save everything else has been saved
+	MaxLines                     int64             // maximum number of lines (if 0 or below, no limit) only used during Load
+	MaxColumns                   int64             // maximum column length (if 0 or below, no limit) only used during Load
+	MaxTags                      int64             // maximum number of tags (if 0 or below, no limit) only used during Load
+	MaxPrintLines                int               // maximum number of lines for printing for console mode, preceding lines are cut off
+	GetWordAtLeft                bool              // if true, word-change event triggers any word left of the caret if the caret is not on a word
+	LiberalGetWordAt             bool              // if true, word boundaries include punctuation but not parentheses (may be useful for Lisp symbol lookup)
+	NormalizeForm                NormalizationForm // Unicode normalization of text set or inserted (default: NormalizeNone)
+	TypeOverSelection            bool              // typing replaces the current selection (default: true)
+	CanBreakBefore               LineBreakFunc     // if set, word wrap may also break between prev and next if true (e.g. CJKCanBreakBefore)
+	OnViewportChange             ViewportFunc      // if set, called in a goroutine after the visible lines or columns have changed, must use Do for editing
+	ViewportChangeDelay          time.Duration     // the viewport change callback is only called when there is no change for this long
+	LineSpacing                  float32           // additional space between lines in Fyne units (default: 0), must be set before creating the editor
+	LineNumberFormat             LineNumberFunc    // if set, formats the line numbers, receiving the line number and the caret line number
+	LineNumberStart              int               // the number displayed for the first line or paragraph (default: 1)
+	ShouldMatchBracketAt         PosPredicate      // if set, only brackets and quotes at positions for which it returns true are matched
+	WrapColumn                   int               // column at which lines are wrapped (if 0 or below, the viewport width is used)
+	EndOfBufferChar              rune              // displayed in the first column of rows below the end of the text (default: space)
+	OnBulkEdit                   BulkEditFunc      // if set, consulted before deleting at least BulkEditThreshold runes, false aborts
+	BulkEditThreshold            int               // minimum number of runes for an edit to count as bulk edit (default: 10000)
+	ControlCharPolicy            ControlCharPolicy // handling of control characters (default: ControlKeep)
+	HighlightTrailingWhitespace  bool              // highlight spaces and tabs at the end of paragraphs with the error color
+	AutoPairQuotes               bool              // typing a quote inserts a pair, typing it again or backspace skips or deletes the pair
+	VirtualSpace                 bool              // the caret may move past the end of a paragraph, spaces are inserted when typing there
+	IndentStrategy               IndentStrategy    // if set, new lines and lines starting with closing brackets are indented automatically
+	SearchTag                    Tag               // template for the tags marking search matches
+	SearchStyler                 TagStyler         // style of search matches (default: theme warning color)
+	GotoContextLines             int               // lines kept visible above and below the target of GotoLine if possible
+	GotoCenter                   bool              // if true, GotoLine and related functions center the target line
+	ProtectedTag                 Tag               // if set, text tagged with tags of the same name cannot be changed (default: nil)
+	ReadOnly                     bool              // if true, the user cannot edit the text, but programmatic changes are possible
+	SoftTabs                     bool              // if true, the tab key inserts TabWidth spaces instead of a tab
+	HoverDelay                   time.Duration     // how long the mouse pointer must rest before tag hover events occur (default: 500ms)
+	ConsoleMode                  bool              // Print only scrolls to the bottom if the view was at the bottom before
+	AutoSize                     bool              // the visible columns and lines are adapted to the size of the widget
+	TrimTrailingWhitespaceOnSave bool              // Save and SaveTextToFile remove spaces and tabs at the end of paragraphs
+	PlaceHolder                  string            // hint displayed in the line number color while the text is empty and unfocused
 }
 
 // NewConfig returns a new config with default values.
@@ -148,6 +253,23 @@ func NewConfig() *Config {
 	z.TagPostRead = TagPostReadFunc(func(tag TagWithInterval) error {
 		return nil
 	})
+	z.SearchTag = NewTag("search")
+	z.SearchStyler = TagStyler{
+		TagName: z.SearchTag.Name(),
+		StyleFunc: TagStyleFunc(func(tag Tag, c Cell) Cell {
+			fg := theme.TextColor()
+			bg := theme.WarningColor()
+			if c.Style != EmptyStyle {
+				if c.Style.FGColor != nil {
+					fg = BlendColors(z.BlendFG, z.BlendFGSwitched, c.Style.FGColor, theme.TextColor())
+				}
+				if c.Style.BGColor != nil {
+					bg = BlendColors(z.BlendBG, z.BlendBGSwitched, c.Style.BGColor, theme.WarningColor())
+				}
+			}
+			return Cell{Rune: c.Rune, Style: Style{FGColor: fg, BGColor: bg}}
+		}),
+	}
 	z.MaxLines = 1000000
 	z.MaxColumns = 1000000
 	z.HighlightTag = NewTag("highlight")
@@ -204,6 +326,7 @@ func NewConfig() *Config {
 	z.CaretBlinkDelay = 3 * time.Second
 	z.CaretOnDuration = 600 * time.Millisecond
 	z.CaretOffDuration = 200 * time.Millisecond
+	z.CaretBlink = true
 	z.DrawCaret = true
 	z.ScrollFactor = 2.0
 	// mark color and style
@@ -217,6 +340,12 @@ func NewConfig() *Config {
 	}
 	z.ParagraphLineNumbers = true
 	z.MaxPrintLines = 10000
+	z.HoverDelay = 500 * time.Millisecond
+	z.TypeOverSelection = true
+	z.ViewportChangeDelay = 100 * time.Millisecond
+	z.LineNumberStart = 1
+	z.EndOfBufferChar = ' '
+	z.BulkEditThreshold = 10000
 	return z
 }
 
@@ -224,6 +353,14 @@ func NewConfig() *Config {
 // for convenience and it's best to only modify it using methods. If there is no method for some
 // operation, chances are high that direct manipulation of internals such as editor.Rows might
 // break in the future.
+//
+// The editor's input handlers and background goroutines are serialized with each other. Methods
+// that modify the editor may be called directly from input handlers, event handlers and keyboard
+// shortcut handlers, but calls from any other goroutine must be wrapped in Do. This includes
+// methods that only read the text such as CharAt, LastPos or GetText, since the text may be
+// modified concurrently by user input or by Print. Methods ending in _Sync, such as Print_Sync,
+// take the lock themselves and are the exception: they must be called from other goroutines, and
+// never from input, event, or shortcut handlers or within Do.
 type Editor struct {
 	widget.BaseWidget
 	Lines   int             // the number of lines displayed
@@ -238,34 +375,92 @@ type Editor struct {
 	caretPos             CharPos
 	caretState           uint32
 	hasCaretBlinking     uint32
+	caretBlinkPaused     uint32
+	closed               uint32
 	caretBlinkCancel     func()
+	caretBlinkMutex      sync.Mutex
+	caretBlinkGen        uint64
 	grid                 *widget.TextGrid
 	scroll               *container.Scroll
 	lineOffset           int
 	columnOffset         int
 	charSize             fyne.Size
 	border               *fyne.Container
-	lastInteraction      time.Time
+	lastInteraction      int64 // UnixNano, accessed atomically since the blink loop reads it
 	defaultStyle         Style
 	invertedDefaultStyle Style
+	markColors           []color.Color
+	themeTextSize        float32
+	themeVariant         fyne.ThemeVariant
 	lineNumberStyle      Style
 	lineNumberGrid       *widget.TextGrid
 	vSpacer              *FixedSpacer
+	hSpacer              *FixedSpacer
+	hScroll              *container.Scroll
 	maxLineLen           int
+	maxLineLenValid      bool
+	paged                *pagedBuffer
 	hasFocus             bool
 	background           *canvas.Rectangle
 	content              *fyne.Container
 	selStart             *CharPos
 	selEnd               *CharPos
+	blockSelection       bool
+	blockTags            []Tag
+	carets               []*Anchor
+	expandStack          []selectionState
+	expandedTo           CharInterval
+	lastChange           Change
+	anchors              map[*Anchor]struct{}
+	contextMenu          *fyne.Menu
+	caretOverlay         *fyne.Container
+	caretRects           []*canvas.Rectangle
+	caretRectsUsed       int
+	gutterOverlay        *fyne.Container
+	gutterMarkers        map[*Anchor]GutterMarker
+	gutterClickLine      int
 	shortcuts            map[string]fyne.KeyboardShortcut
 	handlers             map[string]func(z *Editor)
 	keyHandlers          map[fyne.KeyName]func(z *Editor)
+	movements            map[string]MovementFunc
 	canvas               fyne.Canvas
 	currentWord          string
+	modified             bool
+	fileHash             [sha256.Size]byte
+	lastLineOffset       int
+	lastColumnOffset     int
+	viewportTimer        *time.Timer
+	rowGrids             *fyne.Container
+	lineNumberRows       *fyne.Container
+	virtualLines         []*virtualLine
+	displayLines         []int
+	displayVirtual       []*virtualLine
+	links                map[LinkID]*rangeLink
+	nextLinkID           LinkID
+	mirroring            bool
+	hex                  *hexState
+	virtualSpace         int
+	ruleStylers          []ruleStyler
+	folds                []*foldRegion
+	shownRows            []shownRow
+	shownLineNumbers     []string
+	shownColors          [2]color.Color
+	shownSettings        displaySettings
+	caretGridRows        []int
+	highlighter          Highlighter
+	highlightStates      []HighlightState
+	tokenTheme           TokenTheme
+	searchQuery          string
+	searchOptions        FindOptions
+	hoverTimer           *time.Timer
+	hoveredTags          []TagWithInterval
 	// synchronization
 	refresher     func()
 	lastRefreshed time.Time
+	refreshTimer  *time.Timer
 	mutex         sync.RWMutex
+	editMutex     sync.Mutex
+	closeMutex    sync.RWMutex // read-locked by background goroutines while they draw, see Close
 }
 
 // NewEditor returns a new editor widget with fixed columns and lines, which is displayed in the given
@@ -284,77 +479,59 @@ func NewEditorWithConfig(columns, lines int, c fyne.Canvas, config *Config) *Edi
 	z.Styles = NewStyleContainer()
 	z.canvas = c
 	z.grid = widget.NewTextGrid()
+	z.lineNumberGrid = widget.NewTextGrid()
 	z.initInternalGrid()
 	z.eventHandlers = make(map[EditorEvent]EventHandler)
 	z.shortcuts = make(map[string]fyne.KeyboardShortcut)
 	z.handlers = make(map[string]func(z *Editor))
 	z.keyHandlers = make(map[fyne.KeyName]func(z *Editor))
-	z.lastInteraction = time.Now()
+	z.movements = make(map[string]MovementFunc)
+	z.gutterMarkers = make(map[*Anchor]GutterMarker)
+	z.anchors = make(map[*Anchor]struct{})
+	z.markInteraction()
 	z.caretState = 1
+	z.lastLineOffset = -1
+	z.lastColumnOffset = -1
 	z.Tags = NewTagContainer()
 	_, z.caretBlinkCancel = context.WithCancel(context.Background())
-	z.invertedDefaultStyle = Style{FGColor: theme.InputBackgroundColor(), BGColor: theme.ForegroundColor()}
-	z.defaultStyle = Style{FGColor: theme.ForegroundColor(), BGColor: theme.InputBackgroundColor()}
-	bgcolor := theme.OverlayBackgroundColor()
-	fgcolor := theme.PlaceHolderColor()
-	z.lineNumberStyle = Style{FGColor: fgcolor, BGColor: bgcolor}
 	z.background = canvas.NewRectangle(theme.InputBackgroundColor())
-	z.background.StrokeColor = theme.InputBorderColor()
-	z.background.StrokeWidth = theme.InputBorderSize()
-	z.background.CornerRadius = theme.InputRadiusSize()
-	z.lineNumberGrid = widget.NewTextGrid()
-	z.charSize = fyne.MeasureText("M", theme.TextSize(), fyne.TextStyle{Monospace: true})
+	z.applyTheme()
 
-	z.vSpacer = NewFixedSpacer(fyne.Size{Width: 0, Height: float32(z.Lines) * z.charSize.Height})
+	z.vSpacer = NewFixedSpacer(fyne.Size{Width: 0, Height: float32(z.Lines) * z.RowHeight()})
 
 	z.scroll = container.NewScroll(z.vSpacer)
 	z.scroll.OnScrolled = func(pos fyne.Position) {
-		z.lineOffset = max(0, int(math32.Round(pos.Y/z.charSize.Height)))
+		z.editMutex.Lock()
+		defer z.editMutex.Unlock()
+		z.lineOffset = max(0, int(math32.Round(pos.Y/z.RowHeight())))
 		z.scroll.Offset = pos
 		z.hasFocus = true
 		z.Refresh()
 		z.Focus()
 	}
-	z.border = container.NewBorder(nil, nil, z.lineNumberGrid, z.scroll, z.grid)
-	z.content = container.New(layout.NewStackLayout(), z.background, z.border)
+	z.hSpacer = NewFixedSpacer(fyne.Size{Width: float32(z.Columns) * z.charSize.Width, Height: 0})
+	z.hScroll = container.NewHScroll(z.hSpacer)
+	z.hScroll.OnScrolled = func(pos fyne.Position) {
+		z.editMutex.Lock()
+		defer z.editMutex.Unlock()
+		z.columnOffset = max(0, int(math32.Round(pos.X/z.charSize.Width)))
+		z.hScroll.Offset = pos
+		z.Refresh()
+	}
+	z.hScroll.Hide()
+	z.border = container.NewBorder(nil, z.hScroll, z.lineNumberView(), z.scroll, z.gridView())
+	z.caretOverlay = container.NewWithoutLayout()
+	z.gutterOverlay = container.NewWithoutLayout()
+	z.content = container.New(layout.NewStackLayout(), z.background, z.border, z.gutterOverlay, z.caretOverlay)
 	// selection styler
 	z.Styles.AddStyler(z.Config.SelectionStyler)
 	z.Styles.AddStyler(z.Config.HighlightStyler)
 	z.Styles.AddStyler(z.Config.ErrorStyler)
+	z.Styles.AddStyler(z.Config.SearchStyler)
 	// mark color and style
 
-	col0, _ := colorful.MakeColor(color.RGBA{210, 245, 60, 255})
-	col1, _ := colorful.MakeColor(color.RGBA{255, 215, 180, 255})
-	col2, _ := colorful.MakeColor(color.RGBA{255, 250, 200, 255})
-	col3, _ := colorful.MakeColor(color.RGBA{170, 255, 195, 255})
-	col4, _ := colorful.MakeColor(color.RGBA{220, 190, 255, 255})
-	col5, _ := colorful.MakeColor(color.RGBA{250, 190, 212, 255})
-	col6, _ := colorful.MakeColor(color.RGBA{255, 225, 25, 255})
-	col7, _ := colorful.MakeColor(color.RGBA{0, 130, 200, 255})
-	col8, _ := colorful.MakeColor(color.RGBA{60, 180, 75, 255})
-	col9, _ := colorful.MakeColor(color.RGBA{245, 130, 48, 255})
-
-	markColors := []color.Color{
-		col0,
-		col1,
-		col2,
-		col3,
-		col4,
-		col5,
-		col6,
-		col7,
-		col8,
-		col9,
-	}
-
-	if fyne.CurrentApp().Settings().ThemeVariant() == theme.VariantDark {
-		for i := range markColors {
-			markColors[i] = BlendColors(BlendPhoenix, true, markColors[i], theme.InputBackgroundColor())
-		}
-	}
-
 	markStyler := TagStyleFunc(func(tag Tag, c Cell) Cell {
-		selStyle := Style{FGColor: theme.ForegroundColor(), BGColor: markColors[tag.Index()%10]}
+		selStyle := Style{FGColor: theme.ForegroundColor(), BGColor: z.markColors[tag.Index()%len(z.markColors)]}
 		return Cell{
 			Rune:  c.Rune,
 			Style: selStyle,
@@ -362,11 +539,64 @@ func NewEditorWithConfig(columns, lines int, c fyne.Canvas, config *Config) *Edi
 	})
 	z.Styles.AddStyler(TagStyler{TagName: z.Config.MarkTag.Name(), StyleFunc: markStyler, DrawFullLine: true})
 	z.SetText(" ")
+	z.modified = false
 	z.BlinkCaret(true)
 	z.addDefaultShortcuts()
 	return &z
 }
 
+// ApplyTheme updates the char size, the default, inverted, and line number styles, and the mark colors
+// according to the current theme and refreshes the editor. This is done automatically when the text
+// size or theme variant has changed at the next refresh, but may be called explicitly after other theme
+// changes. A line number style set by SetLineNumberStyle is replaced by the theme's style.
+func (z *Editor) ApplyTheme() {
+	z.applyTheme()
+	z.background.Refresh()
+	z.adjustScroll()
+	z.BaseWidget.Refresh()
+	z.Refresh()
+}
+
+// applyTheme updates the theme-dependent sizes, styles, and colors without refreshing the display.
+func (z *Editor) applyTheme() {
+	z.shownRows = nil
+	z.themeTextSize = theme.TextSize()
+	z.themeVariant = fyne.CurrentApp().Settings().ThemeVariant()
+	z.charSize = fyne.MeasureText("M", z.themeTextSize, fyne.TextStyle{Monospace: true})
+	z.invertedDefaultStyle = Style{FGColor: theme.InputBackgroundColor(), BGColor: theme.ForegroundColor()}
+	z.defaultStyle = Style{FGColor: theme.ForegroundColor(), BGColor: theme.InputBackgroundColor()}
+	z.lineNumberStyle = Style{FGColor: theme.PlaceHolderColor(), BGColor: theme.OverlayBackgroundColor()}
+	z.background.FillColor = theme.InputBackgroundColor()
+	z.background.StrokeColor = theme.InputBorderColor()
+	if z.hasFocus {
+		z.background.StrokeColor = theme.FocusColor()
+	}
+	z.background.StrokeWidth = theme.InputBorderSize()
+	z.background.CornerRadius = theme.InputRadiusSize()
+	z.markColors = []color.Color{
+		color.RGBA{210, 245, 60, 255},
+		color.RGBA{255, 215, 180, 255},
+		color.RGBA{255, 250, 200, 255},
+		color.RGBA{170, 255, 195, 255},
+		color.RGBA{220, 190, 255, 255},
+		color.RGBA{250, 190, 212, 255},
+		color.RGBA{255, 225, 25, 255},
+		color.RGBA{0, 130, 200, 255},
+		color.RGBA{60, 180, 75, 255},
+		color.RGBA{245, 130, 48, 255},
+	}
+	if z.themeVariant == theme.VariantDark {
+		for i := range z.markColors {
+			z.markColors[i] = BlendColors(BlendPhoenix, true, z.markColors[i], theme.InputBackgroundColor())
+		}
+	}
+}
+
+// themeChanged returns true if the text size or theme variant differs from the one last applied.
+func (z *Editor) themeChanged() bool {
+	return theme.TextSize() != z.themeTextSize || fyne.CurrentApp().Settings().ThemeVariant() != z.themeVariant
+}
+
 // MakeOrGetStyleTag creates or returns a tag for given style and foreground and background colors. This method avoids duplicating tags
 // and adds an adequate style function for the tag. It does not define any payload or
 // callback. A style tag has the name "style-bold-italic-monospace-R1,G1,B1,A1-R2,G2,B2,A2" where R is decimal red, G decimal green, B is decimal
@@ -520,11 +750,66 @@ func (z *Editor) RemoveEventHandler(event EditorEvent) {
 }
 
 // adjustScroll adjusts the internal spacer of the scroll bar. This method must be called after each
-// change that might affect the number of rows.
+// change that might affect the number of rows. The scroll offset counts text lines, so the spacer is
+// sized such that the largest scroll offset corresponds to the largest line offset.
 func (z *Editor) adjustScroll() {
-	z.vSpacer.SetHeight(float32(len(z.Rows)) * z.charSize.Height)
+	z.vSpacer.SetHeight(float32(z.maxLineOffset()+z.Lines) * z.RowHeight())
 	pos := z.scroll.Offset
-	z.scroll.Offset = fyne.Position{X: pos.X, Y: max(0, z.charSize.Height*float32(z.lineOffset))}
+	z.scroll.Offset = fyne.Position{X: pos.X, Y: max(0, z.RowHeight()*float32(z.lineOffset))}
+	z.adjustHScroll()
+}
+
+// adjustHScroll shows the horizontal scroll bar if line wrap is off and the longest line does not fit
+// into the visible columns, and hides it otherwise. The spacer is sized such that the largest scroll
+// offset corresponds to the largest column offset at which the longest line ends in the last column.
+func (z *Editor) adjustHScroll() {
+	if z.hScroll == nil {
+		return
+	}
+	maxLen := z.MaxLineLength()
+	if z.Config.LineWrap || maxLen <= z.Columns {
+		if z.hScroll.Visible() {
+			z.hScroll.Hide()
+		}
+		return
+	}
+	z.hSpacer.ChangeSize(fyne.Size{Width: float32(maxLen-z.Columns)*z.charSize.Width + z.hScroll.Size().Width,
+		Height: 0})
+	// an offset beyond the spacer would be clamped by the scroll bar, which then calls OnScrolled
+	z.columnOffset = min(z.columnOffset, z.maxColumnOffset())
+	z.hScroll.Offset = fyne.Position{X: float32(z.columnOffset) * z.charSize.Width, Y: 0}
+	refreshScroll(z.hScroll)
+}
+
+// refreshScroll refreshes the scroll container s after the editor has set its offset, and shows it if
+// it is hidden. Fyne clamps the offset of a refreshed scroll container to its content and calls
+// OnScrolled synchronously if the clamped offset differs from the one that was set, and OnScrolled
+// would then take the edit lock held by the caller. The offset is therefore clamped the same way
+// beforehand, so Fyne finds it unchanged.
+func refreshScroll(s *container.Scroll) {
+	size := s.Size()
+	contentSize := s.Content.Size()
+	if contentSize.Width <= size.Width && contentSize.Height <= size.Height {
+		s.Offset = fyne.Position{}
+	} else {
+		minSize := s.Content.MinSize()
+		s.Offset.X = clampScrollOffset(s.Offset.X, size.Width, minSize.Width)
+		s.Offset.Y = clampScrollOffset(s.Offset.Y, size.Height, minSize.Height)
+	}
+	if !s.Visible() {
+		s.Show()
+		return
+	}
+	s.Refresh()
+}
+
+// clampScrollOffset returns the offset clamped like Fyne clamps the offset of a scroll container of
+// the given outer size to the given content size.
+func clampScrollOffset(offset, outer, inner float32) float32 {
+	if offset+outer >= inner {
+		offset = inner - outer
+	}
+	return max(offset, 0)
 }
 
 // initInternalGrid initializes the internal grid (z.grid) to all spaces Lines x Columns.
@@ -538,6 +823,82 @@ func (z *Editor) initInternalGrid() {
 			z.grid.Rows[i].Cells[j].Style = nil
 		}
 	}
+	// line number rows are preallocated, so row grids can share them
+	z.lineNumberGrid.Rows = make([]widget.TextGridRow, z.Lines)
+	z.shownRows = nil
+	z.rowGrids = z.initRowGrids(z.rowGrids, z.grid.Rows)
+	z.lineNumberRows = z.initRowGrids(z.lineNumberRows, z.lineNumberGrid.Rows)
+}
+
+// initRowGrids creates one single-row text grid per row, each sharing its row with the given rows,
+// and puts them into the container c, which is created if it is nil. These are displayed instead of
+// the internal grid and the line number grid, so that a changed row can be refreshed without
+// rendering the other rows again, and since a TextGrid cannot display rows with line spacing.
+func (z *Editor) initRowGrids(c *fyne.Container, rows []widget.TextGridRow) *fyne.Container {
+	objects := make([]fyne.CanvasObject, len(rows))
+	for i := range rows {
+		g := widget.NewTextGrid()
+		g.Rows = rows[i : i+1 : i+1]
+		objects[i] = g
+	}
+	if c == nil {
+		return container.New(&spacedRowLayout{z: z}, objects...)
+	}
+	c.Objects = objects
+	c.Refresh()
+	return c
+}
+
+// gridView returns the canvas object displaying the internal grid.
+func (z *Editor) gridView() fyne.CanvasObject {
+	return z.rowGrids
+}
+
+// lineNumberView returns the canvas object displaying the line numbers.
+func (z *Editor) lineNumberView() fyne.CanvasObject {
+	return z.lineNumberRows
+}
+
+// setLineNumberCell sets a cell of the line number grid without refreshing it. The row must exist.
+func (z *Editor) setLineNumberCell(row, col int, cell widget.TextGridCell) {
+	for len(z.lineNumberGrid.Rows[row].Cells) <= col {
+		z.lineNumberGrid.Rows[row].Cells = append(z.lineNumberGrid.Rows[row].Cells, widget.TextGridCell{})
+	}
+	z.lineNumberGrid.Rows[row].Cells[col] = cell
+}
+
+// sameColor returns true if the two colors are both nil or have the same RGBA values.
+func sameColor(a, b color.Color) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+// spacedRowLayout lays out single-row text grids vertically with the editor's line spacing between them.
+type spacedRowLayout struct {
+	z *Editor
+}
+
+// Layout places the rows below each other.
+func (l *spacedRowLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	y := float32(0)
+	for _, obj := range objects {
+		obj.Resize(fyne.Size{Width: size.Width, Height: l.z.charSize.Height})
+		obj.Move(fyne.Position{X: 0, Y: y})
+		y += l.z.RowHeight()
+	}
+}
+
+// MinSize returns the size needed for displaying all rows with line spacing.
+func (l *spacedRowLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var width float32
+	for _, obj := range objects {
+		width = max(width, obj.MinSize().Width)
+	}
+	return fyne.Size{Width: width, Height: float32(len(objects)) * l.z.RowHeight()}
 }
 
 // SetLineNumberStyle sets the style of the line number display in terms of an EditorStyle.
@@ -550,10 +911,58 @@ func (z *Editor) SetTopLine(x int) {
 	z.lineOffset = x
 	if z.scroll != nil {
 		pos := z.scroll.Offset
-		z.scroll.Offset = fyne.Position{X: pos.X, Y: max(0, z.charSize.Height*float32(z.lineOffset))}
+		z.scroll.Offset = fyne.Position{X: pos.X, Y: max(0, z.RowHeight()*float32(z.lineOffset))}
 	}
 	z.Refresh()
-	z.scroll.Refresh()
+	refreshScroll(z.scroll)
+}
+
+// SetViewportSize changes the number of columns and lines displayed by the editor. The internal display
+// grid is rebuilt, the text is re-wrapped at the new width if line wrapping is on, and the display
+// is refreshed. The caret and the top line are kept as far as possible. Set Config.AutoSize to let
+// the editor do this automatically whenever its size changes.
+func (z *Editor) SetViewportSize(columns, lines int) {
+	columns = max(1, columns)
+	lines = max(1, lines)
+	z.mutex.Lock()
+	z.Lines = lines
+	z.Columns = columns + 1
+	z.initInternalGrid()
+	z.mutex.Unlock()
+	if z.Config.LineWrap {
+		z.rewrapAll()
+	}
+	z.lineOffset = SafePositiveValue(z.lineOffset, max(0, len(z.Rows)-z.Lines))
+	z.columnOffset = 0
+	z.scrollToCaretColumn()
+	z.SetTopLine(z.lineOffset)
+}
+
+// autoSize changes the number of visible columns and lines to fit the given widget size if
+// Config.AutoSize is set. It is called by the renderer's Layout, which Fyne calls without the edit
+// lock, and the scroll bars must only be resized after it has released the lock, since Fyne may call
+// their OnScrolled handlers synchronously when they are resized.
+func (z *Editor) autoSize(size fyne.Size) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	if !z.Config.AutoSize {
+		return
+	}
+	columns, lines := z.sizeToViewport(size)
+	if columns > 0 && lines > 0 && (columns+1 != z.Columns || lines != z.Lines) {
+		z.SetViewportSize(columns, lines)
+	}
+}
+
+// sizeToViewport returns the number of columns and lines that fit into the given widget size, in the
+// form expected by SetViewportSize.
+func (z *Editor) sizeToViewport(size fyne.Size) (int, int) {
+	width := size.Width - 2*theme.InnerPadding() - theme.ScrollBarSize()
+	if z.Config.ShowLineNumbers {
+		width -= float32(z.lineNumberLen()) * z.charSize.Width
+	}
+	height := size.Height - 2*theme.InnerPadding()
+	return int(width/z.charSize.Width) - 1, int(height / z.RowHeight())
 }
 
 // TopLine returns the topmost visible line.
@@ -561,10 +970,104 @@ func (z *Editor) TopLine() int {
 	return z.lineOffset
 }
 
+// RowHeight returns the height of a displayed row in Fyne units, including line spacing.
+func (z *Editor) RowHeight() float32 {
+	return z.charSize.Height + z.Config.LineSpacing
+}
+
+// LineScreenY returns the y-position of the top of the given line relative to the editor widget and true
+// if the line is currently displayed, 0 and false otherwise. Together with RowHeight this can be used
+// to position other widgets relative to a line.
+func (z *Editor) LineScreenY(line int) (float32, bool) {
+	row, ok := z.gridRowOf(line)
+	if !ok || line > z.LastLine() {
+		return 0, false
+	}
+	return z.gridView().Position().Y + float32(row)*z.RowHeight(), true
+}
+
 // CenterLineOnCaret adjusts the displayed lines such that the caret is in the center of the grid.
 func (z *Editor) CenterLineOnCaret() {
-	line := z.caretPos.Line
-	z.SetTopLine(min(z.LastLine()-z.Lines+1, max(0, line-z.Lines/2)))
+	z.ScrollCaretToFraction(0.5)
+}
+
+// ScrollCaretToFraction adjusts the displayed lines such that the caret line is displayed at the
+// given fraction of the grid height, where 0 is the top line and 1 is the bottom line. The top
+// line is clamped to the text, so the caret may end up elsewhere near the start or end of the text.
+func (z *Editor) ScrollCaretToFraction(f float32) {
+	f = min(max(f, 0), 1)
+	row := min(int(f*float32(z.Lines)), z.Lines-1)
+	z.SetTopLine(SafePositiveValue(z.caretPos.Line-row, z.maxLineOffset()))
+}
+
+// IsProtected returns true if the given position is within a protected region marked by a tag with
+// the name of Config.ProtectedTag. Text cannot be inserted at protected positions and intervals
+// containing them cannot be deleted.
+func (z *Editor) IsProtected(pos CharPos) bool {
+	return z.isProtectedRange(CharInterval{Start: pos, End: pos})
+}
+
+// isProtectedRange returns true if the interval intersects a protected region.
+func (z *Editor) isProtectedRange(interval CharInterval) bool {
+	if z.Config.ProtectedTag == nil {
+		return false
+	}
+	tags, ok := z.Tags.LookupRange(interval)
+	if !ok {
+		return false
+	}
+	for _, tag := range tags {
+		if tag != nil && tag.Name() == z.Config.ProtectedTag.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetReadOnly switches read-only mode on or off, see Config.ReadOnly. In read-only mode, typing, cutting,
+// pasting, and deleting are no-ops, whereas the caret can be moved and text can be selected and copied.
+// Programmatic changes such as Insert, Delete, and SetText still work.
+func (z *Editor) SetReadOnly(on bool) {
+	z.Config.ReadOnly = on
+	z.Refresh()
+}
+
+// GotoLine puts the caret at the start of the given line (0-indexed) and scrolls to it, see GotoLineCol.
+func (z *Editor) GotoLine(line int) {
+	z.GotoLineCol(line, 0)
+}
+
+// GotoLineCol puts the caret at the given line and column (both 0-indexed), which are clamped to valid
+// positions, and scrolls such that the line is visible with Config.GotoContextLines lines above and below
+// it. If Config.GotoCenter is true, the line is centered instead.
+func (z *Editor) GotoLineCol(line, col int) {
+	line = SafePositiveValue(line, z.LastLine())
+	z.SetCaret(CharPos{Line: line, Column: SafePositiveValue(col, z.LastColumn(line))})
+	if z.Config.GotoCenter {
+		z.CenterLineOnCaret()
+		return
+	}
+	context := min(max(z.Config.GotoContextLines, 0), (z.Lines-1)/2)
+	top := z.lineOffset
+	if line-context < top {
+		top = line - context
+	} else if line+context > top+z.Lines-1 {
+		top = line + context - z.Lines + 1
+	}
+	z.scrollToCaretColumn()
+	z.SetTopLine(SafePositiveValue(top, z.maxLineOffset()))
+}
+
+// GotoParagraph puts the caret at the start of the given paragraph (1-indexed) like GotoLine. The
+// paragraph number corresponds to the line numbers shown if Config.ParagraphLineNumbers is true. It
+// returns false if there is no such paragraph.
+func (z *Editor) GotoParagraph(paraNum int) bool {
+	line, ok := z.ParaToLine(paraNum)
+	if !ok {
+		return false
+	}
+	z.GotoLine(line)
+	return true
 }
 
 // LastLine returns the last line (0-indexed).
@@ -574,7 +1077,10 @@ func (z *Editor) LastLine() int {
 
 // LastColumn returns the last column of the given line (both 0-indexed).
 func (z *Editor) LastColumn(n int) int {
-	return len(z.Rows[n]) - 1
+	if z.paged != nil && n == len(z.Rows)-1 && z.Rows[n] == nil {
+		return z.paged.lastColumn
+	}
+	return len(z.row(n)) - 1
 }
 
 // LineText returns the text of line i, the empty string if i is out of bounds.
@@ -582,21 +1088,129 @@ func (z *Editor) LineText(i int) string {
 	if i < 0 || i > z.LastLine() {
 		return ""
 	}
-	return string(z.Rows[i])
+	return string(z.row(i))
 }
 
-// SetRune sets the rune at the given line and column.
+// SetRune sets the rune at the given line and column. Nothing is done if the position is invalid.
 func (z *Editor) SetRune(pos CharPos, r rune) {
+	if !z.ValidPos(pos) {
+		return
+	}
+	z.materialize()
 	z.Rows[pos.Line][pos.Column] = r
+	z.highlightEdit(pos.Line)
+	z.markChanged()
+}
+
+// TrySetRune sets the rune at the given position like SetRune but returns ErrPosOutOfRange
+// if the position is invalid.
+func (z *Editor) TrySetRune(pos CharPos, r rune) error {
+	if !z.ValidPos(pos) {
+		return ErrPosOutOfRange
+	}
+	z.SetRune(pos, r)
+	return nil
+}
+
+// ValidPos returns true if the given position is the position of a char in the text, false otherwise.
+func (z *Editor) ValidPos(pos CharPos) bool {
+	if pos.Line < 0 || pos.Column < 0 || pos.Line > z.LastLine() {
+		return false
+	}
+	return pos.Column <= z.LastColumn(pos.Line)
 }
 
 // SetLine sets the line text. If row is beyond the current size, empty rows are added accordingly.
 func (z *Editor) SetLine(row int, content []rune) {
+	z.materialize()
 	if row > z.LastLine() {
 		rows := makeEmptyRows(row - len(z.Rows) + 1)
 		z.Rows = append(z.Rows, rows...)
 	}
 	z.Rows[row] = content
+	z.highlightAll()
+	z.markChanged()
+}
+
+// SetLines replaces len(lines) paragraphs starting with paragraph number start (0-indexed) with the
+// given lines, which must not contain line feeds. Each line becomes a paragraph and is word wrapped
+// according to the configuration. If there are fewer paragraphs than needed, empty paragraphs are
+// added. Tags within the replaced paragraphs are removed and tags below them are shifted accordingly.
+func (z *Editor) SetLines(start int, lines [][]rune) {
+	if start < 0 {
+		return
+	}
+	z.materialize()
+	startRow, n := 0, 0
+	for n < start {
+		if startRow > z.LastLine() {
+			z.Rows = append(z.Rows, []rune{z.Config.HardLF})
+		}
+		startRow = z.FindParagraphEnd(startRow, z.Config.HardLF) + 1
+		n++
+	}
+	endRow := startRow
+	for range lines {
+		if endRow > z.LastLine() {
+			break
+		}
+		endRow = z.FindParagraphEnd(endRow, z.Config.HardLF) + 1
+	}
+	newRows := make([][]rune, 0, len(lines))
+	for _, line := range lines {
+		r := append(slices.Clone(line), z.Config.HardLF)
+		if z.Config.LineWrap {
+			newRows = append(newRows, z.wrapLine(r)...)
+		} else {
+			newRows = append(newRows, r)
+		}
+	}
+	lineDelta := len(newRows) - (endRow - startRow)
+	change := Change{Removed: z.runeCount(startRow, endRow)}
+	for _, line := range lines {
+		change.Inserted += len(line) + 1
+	}
+	if endRow > startRow {
+		z.Tags.ClearRange(z.linesInterval(startRow, endRow-startRow))
+	}
+	anchors := z.saveAnchors(startRow, endRow-1)
+	if endRow <= z.LastLine() && lineDelta != 0 {
+		tags, _ := z.Tags.LookupRange(z.ToEnd(CharPos{Line: endRow, Column: 0}))
+		for _, tag := range tags {
+			if tag == nil {
+				continue
+			}
+			interval, ok := z.Tags.Lookup(tag)
+			if !ok {
+				continue
+			}
+			if interval.Start.Line >= endRow {
+				interval.Start.Line += lineDelta
+			}
+			if interval.End.Line >= endRow {
+				interval.End.Line += lineDelta
+			}
+			z.Tags.Upsert(tag, interval)
+		}
+	}
+	z.Rows = slices.Replace(z.Rows, startRow, endRow, newRows...)
+	if len(z.Rows) == 0 {
+		z.Rows = append(z.Rows, []rune{z.Config.HardLF})
+	}
+	z.restoreAnchors(anchors, startRow, anchorEdit{removed: change.Removed, inserted: change.Inserted})
+	if z.caretPos.Line >= endRow {
+		z.caretPos.Line += lineDelta
+	} else if z.caretPos.Line >= startRow {
+		line := SafePositiveValue(z.caretPos.Line, z.LastLine())
+		z.caretPos = CharPos{Line: line, Column: SafePositiveValue(z.caretPos.Column, z.LastColumn(line))}
+	}
+	z.highlightAll()
+	z.markChanged()
+	lastRow := SafePositiveValue(startRow+len(newRows)-1, z.LastLine())
+	change.Interval = CharInterval{Start: CharPos{Line: SafePositiveValue(startRow, z.LastLine()), Column: 0},
+		End: CharPos{Line: lastRow, Column: z.LastColumn(lastRow)}}
+	z.notifyChange(change)
+	z.Refresh()
 }
 
 // FindParagraphStart finds the start row of the paragraph in which row is located.
@@ -609,11 +1223,11 @@ func (z *Editor) FindParagraphStart(row int, lf rune) int {
 	if row > z.LastLine() {
 		return z.FindParagraphStart(z.LastLine(), lf)
 	}
-	k := len(z.Rows[row-1])
+	k := len(z.row(row - 1))
 	if k == 0 {
 		return row
 	}
-	if z.Rows[row-1][k-1] == lf {
+	if z.row(row - 1)[k-1] == lf {
 		return row
 	}
 	return z.FindParagraphStart(row-1, lf)
@@ -626,25 +1240,55 @@ func (grid *Editor) FindParagraphEnd(row int, lf rune) int {
 	if row >= len(grid.Rows)-1 {
 		return row
 	}
-	k := len(grid.Rows[row])
+	k := len(grid.row(row))
 	if k == 0 {
 		return row
 	}
-	if grid.Rows[row][k-1] == lf {
+	if grid.row(row)[k-1] == lf {
 		return row
 	}
 	return grid.FindParagraphEnd(row+1, lf)
 }
 
+// ParagraphText returns the text of the paragraph containing the given line, with soft line breaks
+// removed and without the final line feed. If the line does not exist, the empty string is returned.
+func (z *Editor) ParagraphText(line int) string {
+	if line < 0 || line > z.LastLine() {
+		return ""
+	}
+	return z.paragraphString(z.FindParagraphStart(line, z.Config.HardLF), z.FindParagraphEnd(line, z.Config.HardLF))
+}
+
+// ParagraphInterval returns the char interval of the paragraph containing the given line, including
+// the final line feed. If the line does not exist, the interval of the last paragraph is returned.
+func (z *Editor) ParagraphInterval(line int) CharInterval {
+	line = SafePositiveValue(line, z.LastLine())
+	end := z.FindParagraphEnd(line, z.Config.HardLF)
+	return CharInterval{Start: CharPos{Line: z.FindParagraphStart(line, z.Config.HardLF), Column: 0},
+		End: CharPos{Line: end, Column: z.LastColumn(end)}}
+}
+
+// paragraphString returns the text of the rows from start to end without line feeds.
+func (z *Editor) paragraphString(start, end int) string {
+	var r []rune
+	for i := start; i <= end && i <= z.LastLine(); i++ {
+		if row := z.row(i); len(row) > 0 {
+			r = append(r, row[:len(row)-1]...)
+		}
+	}
+	return string(r)
+}
+
 // Text returns the Editor's text as string. Both soft and hard linefeeds are replaced with rune '\n'.
 func (z *Editor) Text() string {
 	var sb strings.Builder
 	for i := range z.Rows {
-		for j := range z.Rows[i][:len(z.Rows[i])-1] {
-			sb.WriteRune(z.Rows[i][j])
+		row := z.row(i)
+		for j := range row[:len(row)-1] {
+			sb.WriteRune(row[j])
 		}
 		if i < len(z.Rows) {
-			if z.Rows[i][len(z.Rows[i])-1] == z.Config.HardLF {
+			if row[len(row)-1] == z.Config.HardLF {
 				sb.WriteRune('\n')
 			} // TODO: Check - Should there be a ' ' with SoftLF? Or should it be dropped? There might be an ambiguity.
 		}
@@ -663,83 +1307,409 @@ func (z *Editor) SetMark(n int) {
 	z.Refresh()
 }
 
-// Cut removes the selection text and corresponding tags.
-func (z *Editor) Cut() {
-	sel, ok := z.Tags.Lookup(z.Config.SelectionTag)
+// GotoMark moves the caret to the start of the region marked by the mark with the given number and
+// scrolls it into view. It returns false if the number is not a valid mark tag index or the mark is
+// not set.
+func (z *Editor) GotoMark(n int) bool {
+	if n < 0 || n >= len(z.Config.MarkTags) {
+		return false
+	}
+	interval, ok := z.Tags.Lookup(z.Config.MarkTags[n])
 	if !ok {
-		return
+		return false
 	}
-	z.Delete(sel)
+	z.gotoMarkInterval(interval)
+	return true
 }
 
-// ScrollDown scrolls down the editor's line display by one line.
-func (z *Editor) ScrollDown() {
-	li := min(len(z.Rows)-z.Lines/2, z.lineOffset+1)
-	z.SetTopLine(li)
+// NextMark moves the caret to the start of the next marked region after the caret, regardless of the
+// mark number, and wraps around at the end of the text. It returns false if no mark is set.
+func (z *Editor) NextMark() bool {
+	tag, ok := z.Tags.NextTag(z.Config.MarkTag.Name(), z.caretPos)
+	if !ok {
+		marks := z.Tags.TagsByNameSorted(z.Config.MarkTag.Name())
+		if len(marks) == 0 {
+			return false
+		}
+		tag = marks[0]
+	}
+	z.gotoMarkInterval(tag.Interval)
+	return true
 }
 
-// ScrollUp scrolls up the editor's line display by one line.
-func (z *Editor) ScrollUp() {
-	li := max(0, z.lineOffset-1)
-	z.SetTopLine(li)
+// PrevMark moves the caret to the start of the previous marked region before the caret, regardless of
+// the mark number, and wraps around at the start of the text. It returns false if no mark is set.
+func (z *Editor) PrevMark() bool {
+	tag, ok := z.Tags.PrevTag(z.Config.MarkTag.Name(), z.caretPos)
+	if !ok {
+		marks := z.Tags.TagsByNameSorted(z.Config.MarkTag.Name())
+		if len(marks) == 0 {
+			return false
+		}
+		tag = marks[len(marks)-1]
+	}
+	z.gotoMarkInterval(tag.Interval)
+	return true
 }
 
-// ScrollRight scrolls to the right by n chars but keeps some chars in display if n higher than the line.
-func (z *Editor) ScrollRight(n int) {
-	z.columnOffset = min(z.maxLineLen-z.Columns/2, z.columnOffset+n)
-	z.Refresh()
+// Marks returns the intervals of all marked regions in document order. The number of the mark of an
+// interval can be obtained by looking up the mark tags in z.Tags.
+func (z *Editor) Marks() []CharInterval {
+	marks := z.Tags.TagsByNameSorted(z.Config.MarkTag.Name())
+	result := make([]CharInterval, len(marks))
+	for i := range marks {
+		result[i] = marks[i].Interval
+	}
+	return result
 }
 
-// ScrollLeft scrolls to the left by n chars or until the first char if n is too large.
-func (z *Editor) ScrollLeft(n int) {
-	z.columnOffset = max(0, z.columnOffset-n)
+// ClearMark removes the mark with the given number. It returns false if the number is not a valid
+// mark tag index or the mark was not set.
+func (z *Editor) ClearMark(n int) bool {
+	if n < 0 || n >= len(z.Config.MarkTags) {
+		return false
+	}
+	if !z.Tags.Delete(z.Config.MarkTags[n]) {
+		return false
+	}
 	z.Refresh()
+	return true
 }
 
-// FocusGained implements a Focusable.
-func (z *Editor) FocusGained() {
-	z.hasFocus = true
-	z.background.StrokeColor = theme.FocusColor()
-	z.background.Refresh()
+// gotoMarkInterval puts the caret at the start of the interval and scrolls to it.
+func (z *Editor) gotoMarkInterval(interval CharInterval) {
+	z.SetCaret(interval.Start)
+	z.scrollToCaret()
 	z.Refresh()
 }
 
-// FocusLost implements a Focusable.
-func (z *Editor) FocusLost() {
-	z.hasFocus = false
-	z.background.StrokeColor = theme.InputBorderColor()
-	z.background.Refresh()
-	z.Refresh()
+// Cut copies the selection text to the clipboard and removes it with the corresponding tags.
+func (z *Editor) Cut() {
+	sel, ok := z.Tags.Lookup(z.Config.SelectionTag)
+	if z.Config.ReadOnly || !ok || !z.confirmBulkEdit(sel) {
+		return
+	}
+	z.Copy()
+	if z.deleteBlockSelection() {
+		return
+	}
+	z.Delete(sel)
 }
 
-// Focus sets focus to the editor.
-func (z *Editor) Focus() {
+// Copy puts the selection text onto the clipboard of the window containing the editor. Nothing is
+// done if there is no selection.
+func (z *Editor) Copy() {
+	if _, ok := z.CurrentSelection(); !ok {
+		return
+	}
+	if cb := z.clipboard(); cb != nil {
+		cb.SetContent(z.CurrentSelectionText())
+	}
+}
+
+// Paste inserts the clipboard content at the caret, replacing the selection if there is one, and
+// puts the caret after the inserted text. Lines are separated by line feeds in the same way as if
+// they were typed. If Config.IndentStrategy is set, the pasted lines are reindented according to the
+// brackets around them. Nothing is pasted if the selection intersects a protected region.
+func (z *Editor) Paste() {
+	cb := z.clipboard()
+	if z.Config.ReadOnly || cb == nil {
+		return
+	}
+	s := strings.ReplaceAll(cb.Content(), "\r\n", "\n")
+	if s == "" {
+		return
+	}
+	if sel, ok := z.CurrentSelection(); ok {
+		if z.isProtectedRange(sel) || !z.confirmBulkEdit(sel) {
+			return
+		}
+		z.Delete(sel)
+		z.SetCaret(sel.Start)
+	}
+	z.fillVirtualSpace()
+	start := z.caretPos
+	z.insertText(s)
+	z.reindentPasted(start)
+	z.Refresh()
+}
+
+// insertText inserts the string at the caret and puts the caret after it. Lines are separated by
+// line feeds in the same way as if they were typed, but without automatic indentation.
+func (z *Editor) insertText(s string) {
+	strategy := z.Config.IndentStrategy
+	z.Config.IndentStrategy = nil
+	defer func() { z.Config.IndentStrategy = strategy }()
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		pos, r := z.composeInput([]rune(line), z.caretPos)
+		z.Insert([]rune(line), z.caretPos)
+		z.SetCaret(z.advancePos(pos, len(r)))
+		if i < len(lines)-1 {
+			z.insertLineBreak()
+		}
+	}
+}
+
+// advancePos returns the position n chars after pos, where soft line feeds are not counted, or the
+// last position if there are fewer chars.
+func (z *Editor) advancePos(pos CharPos, n int) CharPos {
+	for n > 0 {
+		c, _ := z.CharAt(pos)
+		next, ok := z.NextPos(pos)
+		if !ok {
+			break
+		}
+		if c != z.Config.SoftLF {
+			n--
+		}
+		pos = next
+	}
+	return pos
+}
+
+// clipboard returns the clipboard of the window containing the editor, or nil if there is none.
+func (z *Editor) clipboard() fyne.Clipboard {
+	app := fyne.CurrentApp()
+	if app == nil {
+		return nil
+	}
+	canvas := app.Driver().CanvasForObject(z)
+	for _, w := range app.Driver().AllWindows() {
+		if canvas == nil || w.Canvas() == canvas {
+			return w.Clipboard()
+		}
+	}
+	return nil
+}
+
+// maybeDeleteSelection deletes the current selection and puts the caret at its start if there
+// is a selection and Config.TypeOverSelection is true. It returns false if the selection intersects
+// a protected region or Config.OnBulkEdit declined the deletion, in which case the input that was to
+// replace the selection must be dropped.
+func (z *Editor) maybeDeleteSelection() bool {
+	if !z.Config.TypeOverSelection {
+		return true
+	}
+	sel, ok := z.CurrentSelection()
+	if !ok {
+		return true
+	}
+	if z.isProtectedRange(sel) || !z.confirmBulkEdit(sel) {
+		return false
+	}
+	z.SetCaret(sel.Start)
+	z.Delete(sel)
+	return true
+}
+
+// ScrollDown scrolls down the editor's line display by one line.
+func (z *Editor) ScrollDown() {
+	li := min(z.maxLineOffset(), z.lineOffset+1)
+	z.SetTopLine(li)
+}
+
+// maxLineOffset returns the largest line offset for scrolling, at which the last line of the text
+// and the virtual lines below it are displayed in the bottom rows.
+func (z *Editor) maxLineOffset() int {
+	if len(z.virtualLines) == 0 {
+		return max(0, len(z.Rows)-z.Lines)
+	}
+	counts := z.virtualLineCounts()
+	rows := 0
+	for line := len(z.Rows) - 1; line >= 0; line-- {
+		rows += 1 + counts[line]
+		if rows > z.Lines {
+			return min(line+1, len(z.Rows)-1)
+		}
+	}
+	return 0
+}
+
+// ScrollUp scrolls up the editor's line display by one line.
+func (z *Editor) ScrollUp() {
+	li := max(0, z.lineOffset-1)
+	z.SetTopLine(li)
+}
+
+// ScrollRight scrolls to the right by n chars, but at most until the end of the longest line is
+// displayed in the last column.
+func (z *Editor) ScrollRight(n int) {
+	z.columnOffset = max(0, min(z.maxColumnOffset(), z.columnOffset+n))
+	z.Refresh()
+}
+
+// maxColumnOffset returns the largest column offset, at which the longest line ends in the last
+// column. It corresponds to the largest offset of the horizontal scroll bar.
+func (z *Editor) maxColumnOffset() int {
+	return max(0, z.MaxLineLength()-z.Columns)
+}
+
+// MaxLineLength returns the length of the longest row in the text, including its line ending.
+// The value is cached and only recomputed after the text has changed. Only the rows of a custom
+// buffer that are currently loaded are taken into account.
+func (z *Editor) MaxLineLength() int {
+	if z.maxLineLenValid {
+		return z.maxLineLen
+	}
+	z.maxLineLen = 0
+	for i := range z.Rows {
+		z.maxLineLen = max(z.maxLineLen, len(z.Rows[i]))
+	}
+	z.maxLineLenValid = true
+	return z.maxLineLen
+}
+
+// markChanged marks the text as modified and invalidates information computed from the text.
+func (z *Editor) markChanged() {
+	z.modified = true
+	z.maxLineLenValid = false
+}
+
+// LastChange returns a description of the last change of the text. It is intended to be called by
+// OnChangeEvent handlers to update external models incrementally.
+func (z *Editor) LastChange() Change {
+	return z.lastChange
+}
+
+// notifyChange records the change and calls the OnChangeEvent handler.
+func (z *Editor) notifyChange(change Change) {
+	z.lastChange = change
+	if handler, ok := z.eventHandlers[OnChangeEvent]; ok && handler != nil {
+		handler(OnChangeEvent, z)
+	}
+}
+
+// runeCount returns the number of runes in the rows from startRow to endRow (exclusive), counting
+// hard line feeds but not soft line feeds. Rows of a custom buffer that have not been loaded are
+// not counted, so that the buffer is not read completely.
+func (z *Editor) runeCount(startRow, endRow int) int {
+	n := 0
+	for i := max(startRow, 0); i < endRow && i < len(z.Rows); i++ {
+		n += len(z.Rows[i])
+		if k := len(z.Rows[i]); k > 0 && z.Rows[i][k-1] == z.Config.SoftLF {
+			n--
+		}
+	}
+	return n
+}
+
+// ScrollLeft scrolls to the left by n chars or until the first char if n is too large.
+func (z *Editor) ScrollLeft(n int) {
+	z.columnOffset = max(0, z.columnOffset-n)
+	z.Refresh()
+}
+
+// FocusGained implements a Focusable.
+func (z *Editor) FocusGained() {
+	z.hasFocus = true
+	z.background.StrokeColor = theme.FocusColor()
+	z.background.Refresh()
+	z.Refresh()
+}
+
+// FocusLost implements a Focusable.
+func (z *Editor) FocusLost() {
+	z.hasFocus = false
+	z.background.StrokeColor = theme.InputBorderColor()
+	z.background.Refresh()
+	z.Refresh()
+}
+
+// Focus sets focus to the editor.
+func (z *Editor) Focus() {
 	z.canvas.Focus(z)
 }
 
 func (z *Editor) MouseIn(evt *desktop.MouseEvent) {}
 
-func (z *Editor) MouseMoved(evt *desktop.MouseEvent) {}
+// MouseMoved determines the tags under the mouse pointer once it has rested for Config.HoverDelay.
+// If they have changed, the TagHoverEvent handler is called and the tags' callbacks are called with
+// MouseHoverEvent.
+func (z *Editor) MouseMoved(evt *desktop.MouseEvent) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	pos := z.PosToCharPos(evt.Position)
+	if z.hoverTimer != nil {
+		z.hoverTimer.Stop()
+	}
+	if z.isClosed() {
+		return
+	}
+	z.hoverTimer = time.AfterFunc(z.Config.HoverDelay, func() {
+		defer z.recoverIfClosed()
+		if z.isClosed() {
+			return
+		}
+		z.editMutex.Lock()
+		defer z.editMutex.Unlock()
+		var tags []TagWithInterval
+		if !pos.IsLineNumber {
+			tags = z.TagsAt(pos)
+		}
+		z.setHoveredTags(tags)
+	})
+}
+
+// MouseOut ends hovering over tags.
+func (z *Editor) MouseOut() {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	if z.hoverTimer != nil {
+		z.hoverTimer.Stop()
+	}
+	z.setHoveredTags(nil)
+}
+
+// HoveredTags returns the tags under the resting mouse pointer with their intervals.
+func (z *Editor) HoveredTags() []TagWithInterval {
+	return z.hoveredTags
+}
 
-func (z *Editor) MouseOut() {}
+// setHoveredTags sets the hovered tags and calls the event handler and tag callbacks if they
+// have changed.
+func (z *Editor) setHoveredTags(tags []TagWithInterval) {
+	if slices.Equal(tags, z.hoveredTags) {
+		return
+	}
+	z.hoveredTags = tags
+	for _, tag := range tags {
+		if cb := tag.Tag.Callback(); cb != nil {
+			cb(MouseHoverEvent, tag.Tag, tag.Interval)
+		}
+	}
+	if handler, ok := z.eventHandlers[TagHoverEvent]; ok && handler != nil {
+		handler(TagHoverEvent, z)
+	}
+}
 
 func (z *Editor) Scrolled(evt *fyne.ScrollEvent) {
-	step := z.Config.ScrollFactor * (evt.Scrolled.DY / z.charSize.Height)
-	z.lineOffset = min(len(z.Rows)-z.Lines/2, max(0, int(float32(z.lineOffset)-step)))
-	z.scroll.Offset = fyne.Position{X: z.scroll.Offset.X, Y: float32(z.lineOffset) * z.charSize.Height}
-	z.scroll.Refresh()
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	step := z.Config.ScrollFactor * (evt.Scrolled.DY / z.RowHeight())
+	z.lineOffset = min(z.maxLineOffset(), max(0, int(float32(z.lineOffset)-step)))
+	z.scroll.Offset = fyne.Position{X: z.scroll.Offset.X, Y: float32(z.lineOffset) * z.RowHeight()}
+	refreshScroll(z.scroll)
 	z.Refresh()
 }
 
 func (z *Editor) Dragged(evt *fyne.DragEvent) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	pos := z.PosToCharPos(evt.Position)
 	if z.selStart == nil {
 		z.selStart = &pos
 		return
 	}
 	z.selEnd = &pos
-	interval := CharInterval{Start: *z.selStart, End: *z.selEnd}.MaybeSwap()
-	z.Tags.Upsert(z.Config.SelectionTag, interval)
+	if currentKeyModifiers()&fyne.KeyModifierAlt != 0 {
+		z.setBlockSelection(*z.selStart, pos)
+	} else {
+		z.clearBlockTags()
+		interval := CharInterval{Start: *z.selStart, End: *z.selEnd}.MaybeSwap()
+		z.Tags.Upsert(z.Config.SelectionTag, interval)
+	}
+	z.handleSelectionChange()
 	if pos.Line <= z.lineOffset {
 		z.ScrollUp()
 		return
@@ -759,13 +1729,42 @@ func (z *Editor) Cursor() desktop.Cursor {
 }
 
 func (z *Editor) Tapped(evt *fyne.PointEvent) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	pos := z.PosToCharPos(evt.Position)
+	if currentKeyModifiers()&fyne.KeyModifierAlt != 0 && !pos.IsLineNumber {
+		z.AddCaret(pos)
+		z.Focus()
+		return
+	}
+	z.ClearCarets()
 	z.SetCaret(pos)
 	z.Focus()
 	z.RemoveSelection()
+	if pos.IsLineNumber {
+		z.handleGutterClick(pos.Line)
+		return
+	}
+	z.handleTagClick(pos)
+}
+
+// handleTagClick calls the callbacks of the tags at the clicked position with TagCtrlClickEvent if
+// the control key is held and with TagClickEvent otherwise.
+func (z *Editor) handleTagClick(pos CharPos) {
+	evt := TagClickEvent
+	if currentKeyModifiers()&fyne.KeyModifierControl != 0 {
+		evt = TagCtrlClickEvent
+	}
+	for _, tag := range z.TagsAt(pos) {
+		if cb := tag.Tag.Callback(); cb != nil {
+			cb(evt, tag.Tag, tag.Interval)
+		}
+	}
 }
 
 func (z *Editor) DoubleTapped(evt *fyne.PointEvent) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	pos := z.PosToCharPos(evt.Position)
 	z.SetCaret(pos)
 	z.Focus()
@@ -773,10 +1772,25 @@ func (z *Editor) DoubleTapped(evt *fyne.PointEvent) {
 }
 
 func (z *Editor) DragEnd() {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	z.selStart = nil
 	z.selEnd = nil
 }
 
+// TagsAt returns all tags whose intervals contain the given position together with their intervals,
+// sorted by the start position of their intervals. It may be used for showing tooltips or context
+// menus for tags, and is safe to call while the text is edited.
+func (z *Editor) TagsAt(pos CharPos) []TagWithInterval {
+	return z.Tags.TagsInRange(CharInterval{Start: pos, End: pos})
+}
+
+// TagsIn returns all tags whose intervals intersect the given interval together with their intervals,
+// sorted by the start position of their intervals. It is safe to call while the text is edited.
+func (z *Editor) TagsIn(interval CharInterval) []TagWithInterval {
+	return z.Tags.TagsInRange(interval.MaybeSwap())
+}
+
 // SELECTION HANDLING
 
 // CurrentSelection returns the CharInterval if there is a non-empty selection marked,
@@ -789,61 +1803,45 @@ func (z *Editor) CurrentSelection() (CharInterval, bool) {
 	return sel, true
 }
 
-// CurrentSelectionText obtains the current text selection.
+// Selections returns all current selection ranges in document order. There is one range per line
+// for a block selection and at most one range otherwise.
+func (z *Editor) Selections() []CharInterval {
+	result := make([]CharInterval, 0, 1+len(z.blockTags))
+	if sel, ok := z.CurrentSelection(); ok {
+		result = append(result, sel)
+	}
+	for _, tag := range z.blockTags {
+		if sel, ok := z.Tags.Lookup(tag); ok {
+			result = append(result, sel)
+		}
+	}
+	return result
+}
+
+// CurrentSelectionText obtains the current text selection. If there are multiple selection ranges,
+// their texts are joined by newlines.
 func (z *Editor) CurrentSelectionText() string {
-	sel, hasSelection := z.Tags.Lookup(z.Config.SelectionTag)
-	if !hasSelection {
-		return ""
+	sels := z.Selections()
+	texts := make([]string, len(sels))
+	for i := range sels {
+		texts[i] = z.GetTextRange(sels[i])
 	}
-	return z.GetTextRange(sel)
+	return strings.Join(texts, "\n")
 }
 
-// SelectWord selects the word under pos if there is one, removes the selection in any case.
+// SelectWord selects the word under pos if there is one, removes the selection in any case. The word
+// is determined in the same way as for word change events, taking Config.LiberalGetWordAt into account.
 func (z *Editor) SelectWord(pos CharPos) {
 	z.RemoveSelection()
-	if z.Config.LiberalGetWordAt {
-		word, fromTo := z.getWordAt(pos)
-		if word != "" {
-			z.Tags.Upsert(z.Config.SelectionTag, fromTo)
-			z.Refresh()
-			if handler, ok := z.eventHandlers[SelectWordEvent]; ok {
-				handler(SelectWordEvent, z)
-			}
-		}
-		return
-	}
-	if pos.Line >= len(z.Rows) {
-		return
-	}
-	if pos.Column >= len(z.Rows[pos.Line]) {
-		return
-	}
-	var wStart, wEnd int
-	j := pos.Column
-	for i := pos.Column; i >= 0; i-- {
-		c := z.Rows[pos.Line][i]
-		if !(unicode.IsLetter(c) || unicode.IsNumber(c)) {
-			wStart = j
-			break
-		}
-		j = i
-	}
-	j = pos.Column
-	for i := pos.Column; i < len(z.Rows[pos.Line]); i++ {
-		c := z.Rows[pos.Line][i]
-		if !(unicode.IsLetter(c) || unicode.IsNumber(c)) {
-			wEnd = j
-			break
-		}
-		j = i
-	}
-	if wEnd == 0 {
+	word, fromTo := z.getWordAt(pos)
+	if word == "" {
 		return
 	}
-	z.selStart = &CharPos{Line: pos.Line, Column: wStart}
-	z.selEnd = &CharPos{Line: pos.Line, Column: wEnd}
-	z.Tags.Upsert(z.Config.SelectionTag, CharInterval{Start: *z.selStart, End: *z.selEnd})
+	z.selStart = &fromTo.Start
+	z.selEnd = &fromTo.End
+	z.Tags.Upsert(z.Config.SelectionTag, fromTo)
 	z.Refresh()
+	z.handleSelectionChange()
 	if handler, ok := z.eventHandlers[SelectWordEvent]; ok {
 		handler(SelectWordEvent, z)
 	}
@@ -852,61 +1850,75 @@ func (z *Editor) SelectWord(pos CharPos) {
 // Select the given char interval. The interval is sanitized before setting the selection.
 func (z *Editor) Select(fromTo CharInterval) {
 	fromTo = fromTo.Sanitize(z.LastPos())
+	z.clearBlockTags()
 	z.Tags.Upsert(z.Config.SelectionTag, fromTo)
 	z.Refresh()
+	z.handleSelectionChange()
 }
 
 // SelectAll selects all text in the editor.
 func (z *Editor) SelectAll() {
 	fromTo := CharInterval{Start: CharPos{Line: 0, Column: 0}, End: z.LastPos()}
+	z.clearBlockTags()
 	z.Tags.Upsert(z.Config.SelectionTag, fromTo)
 	z.Refresh()
+	z.handleSelectionChange()
 }
 
 // RemoveSelection removes the current selection, both the range returned by GetSelection
 // and its graphical display.
 func (z *Editor) RemoveSelection() {
+	_, hadSelection := z.CurrentSelection()
 	z.Tags.Delete(z.Config.SelectionTag)
+	z.clearBlockTags()
 	z.selStart = nil
 	z.selEnd = nil
 	z.Refresh()
+	if hadSelection {
+		z.handleSelectionChange()
+	}
+}
+
+// handleSelectionChange calls the handler for SelectionChangeEvent if there is one.
+func (z *Editor) handleSelectionChange() {
+	if handler, ok := z.eventHandlers[SelectionChangeEvent]; ok && handler != nil {
+		handler(SelectionChangeEvent, z)
+	}
 }
 
 // PosToCharPos converts an internal position of the widget in Fyne's pixel unit to a
-// line, row pair.
+// line, row pair. A position below the last line yields the end of the document and a position
+// past the end of a line yields the line's last column.
 func (z *Editor) PosToCharPos(pos fyne.Position) CharPos {
-	x := pos.X - z.lineNumberGrid.Size().Width
+	x := pos.X - z.lineNumberView().Size().Width
 	y := pos.Y
-	if z.lineNumberGrid.Visible() && pos.X < z.lineNumberGrid.Size().Width {
-		return CharPos{z.lineOffset + int(y/z.charSize.Height), 0, true}
+	row := max(0, z.lineAtGridRow(int(y/z.RowHeight())))
+	if z.lineNumberView().Visible() && pos.X < z.lineNumberView().Size().Width {
+		return CharPos{min(row, z.LastLine()), 0, true}
+	}
+	if row > z.LastLine() {
+		return z.LastPos()
 	}
-	row := z.lineOffset + int(y/z.charSize.Height)
 	s := z.GetLineText(row)
 	if z.columnOffset > 0 {
 		s = substring(s, z.columnOffset, len(s))
 	}
 	column := z.findCharColumn(s, x)
-	return CharPos{row, column + z.columnOffset, false}
+	return CharPos{row, min(max(0, column+z.columnOffset), z.LastColumn(row)), false}
 }
 
-// findCharColumn goes through a line explicitly and measures the position of each char in order to
-// precisely determine a char position based on an x-coordinate. The original code was:
-//
-//	CharPos{z.lineOffset + int(y/z.charSize.Height), int(math32.Round(x / z.charSize.Width)), false}
-//
-// This is extremely imprecise because every character has a different width.
+// findCharColumn returns the column of the char in s displayed at the x-coordinate. The text grid
+// places every rune into a cell of the same width, the rounded width of "M" in the monospace font,
+// regardless of the width of its glyph. Hence, the column is found by dividing by the cell width
+// rather than by measuring the text, which would misplace the caret after wide glyphs such as CJK
+// characters or emoji.
 func (z *Editor) findCharColumn(s string, x float32) int {
-	var sb strings.Builder
-	offset := float32(0)
-	for pos, char := range s {
-		sb.WriteRune(char)
-		size := fyne.MeasureText(sb.String(), theme.TextSize(), fyne.TextStyle{Monospace: true})
-		if size.Width-offset > x {
-			return max(0, pos-1)
-		}
-		offset = offset + z.Config.CharDrift // TODO CHANGE! ad hoc value
+	cellWidth := math32.Round(z.charSize.Width)
+	if cellWidth <= 0 {
+		return 0
 	}
-	return len(s) - 1
+	column := int(x / cellWidth)
+	return max(0, min(column, utf8.RuneCountInString(s)-1))
 }
 
 // GetLineText obtains the text of a single line. The empty string is returned if there is no valid line.
@@ -914,7 +1926,7 @@ func (z *Editor) GetLineText(row int) string {
 	if row < 0 || row > z.LastLine() {
 		return ""
 	}
-	return string(z.Rows[row])
+	return string(z.row(row))
 }
 
 // MinSize returns the minimum size, which is calculated from the Columns
@@ -922,10 +1934,10 @@ func (z *Editor) GetLineText(row int) string {
 func (z *Editor) MinSize() fyne.Size {
 	if !z.Config.ShowLineNumbers {
 		return fyne.Size{Width: float32(z.Columns)*z.charSize.Width + 2*theme.InnerPadding(),
-			Height: float32(z.Lines)*z.charSize.Height + 2*theme.InnerPadding()}
+			Height: float32(z.Lines)*z.RowHeight() + 2*theme.InnerPadding()}
 	}
 	return fyne.Size{Width: float32(z.lineNumberLen())*z.charSize.Width + float32(z.Columns)*z.charSize.Width + 2*theme.InnerPadding(),
-		Height: float32(z.Lines)*z.charSize.Height + 2*theme.InnerPadding()}
+		Height: float32(z.Lines)*z.RowHeight() + 2*theme.InnerPadding()}
 	// TODO: The inner padding is used in the layout. However, the width tends to be much too large
 	// when using charSize, which is based on "M" character and theme settings.
 	// This ought not be the case. If 2*theme.InnerPadding() is removed, the size of the widget may become too small for
@@ -938,46 +1950,150 @@ func (z *Editor) MinSize() fyne.Size {
 func (z *Editor) SetText(s string) {
 	z.Tags.Clear()
 	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = z.normalize(s)
+	if z.Config.ControlCharPolicy == ControlStrip {
+		s = string(z.stripControlChars([]rune(s)))
+	}
 	// s = strings.ReplaceAll(s, "\t", "    ")
 	lines := strings.Split(s, "\n")
+	z.setParagraphs(len(lines), func(i int) []rune { return []rune(lines[i]) })
+}
+
+// setParagraphs replaces the text by n paragraphs obtained from the given function, which returns the
+// runes of a paragraph without line feed, and calls the change event handler.
+func (z *Editor) setParagraphs(n int, paragraph func(i int) []rune) {
 	// populate the text grid
-	z.Rows = make([][]rune, 0)
-	for _, line := range lines {
-		r := []rune(line)
-		r = append(r, z.Config.HardLF)
-		newLines := make([][]rune, 0)
+	removed := z.runeCount(0, len(z.Rows))
+	z.paged = nil
+	z.anchors = make(map[*Anchor]struct{})
+	z.Rows = make([][]rune, 0, n)
+	for i := range n {
+		r := append(slices.Clone(paragraph(i)), z.Config.HardLF)
 		if z.Config.LineWrap {
-			newLines = append(newLines, z.wrapLine(r)...)
+			z.Rows = append(z.Rows, z.wrapLine(r)...)
 		} else {
-			newLines = append(newLines, r)
-		}
-		z.Rows = append(z.Rows, newLines...)
-		if len(z.Rows[len(z.Rows)-1]) > z.maxLineLen {
-			z.maxLineLen = len(z.Rows[len(z.Rows)-1])
+			z.Rows = append(z.Rows, r)
 		}
 	}
+	z.highlightAll()
 	z.maybeHandleWordChangeEvent(z.caretPos)
-	handler, ok := z.eventHandlers[OnChangeEvent]
-	if ok && handler != nil {
-		handler(OnChangeEvent, z)
-	}
+	z.markChanged()
+	z.notifyChange(Change{Interval: CharInterval{Start: CharPos{Line: 0, Column: 0}, End: z.LastPos()},
+		Inserted: z.runeCount(0, len(z.Rows)), Removed: removed})
 	z.Refresh()
 }
 
+// SetTextPreservingView sets the text like SetText but afterwards restores the caret line and column
+// and the top line as far as possible. The positions are clamped to the new text. This is useful for
+// reloading a text that was changed externally without moving the user's view to the top.
+func (z *Editor) SetTextPreservingView(s string) {
+	caret := z.caretPos
+	top := z.lineOffset
+	z.SetText(s)
+	line := SafePositiveValue(caret.Line, z.LastLine())
+	column := SafePositiveValue(caret.Column, z.LastColumn(line))
+	z.SetCaret(CharPos{Line: line, Column: column})
+	z.SetTopLine(SafePositiveValue(top, max(0, z.LastLine()-z.Lines+1)))
+}
+
+// normForm returns the Unicode normalization form set in the configuration, or false if text is not
+// normalized.
+func (z *Editor) normForm() (norm.Form, bool) {
+	switch z.Config.NormalizeForm {
+	case NormalizeNFC:
+		return norm.NFC, true
+	case NormalizeNFD:
+		return norm.NFD, true
+	default:
+		return norm.NFC, false
+	}
+}
+
+// normalize returns the string in the Unicode normalization form set in the configuration.
+func (z *Editor) normalize(s string) string {
+	if form, ok := z.normForm(); ok {
+		return form.String(s)
+	}
+	return s
+}
+
+// prepareInput returns the runes normalized and with control characters stripped according to the
+// configuration. Since this may change the number of runes, callers that move the caret after
+// inserting must use the length of the runes returned by composeInput. Applying it twice has no
+// further effect.
+func (z *Editor) prepareInput(r []rune) []rune {
+	if z.Config.NormalizeForm != NormalizeNone {
+		r = []rune(z.normalize(string(r)))
+	}
+	if z.Config.ControlCharPolicy == ControlStrip {
+		r = z.stripControlChars(r)
+	}
+	return r
+}
+
+// composeInput returns the position at which Insert puts the runes at pos and the runes it inserts
+// there, prepared like prepareInput. If the runes start with a combining character under Unicode
+// normalization, they are normalized together with the grapheme cluster before pos in its row, which
+// Insert replaces, so that e.g. an acute accent typed after an e becomes é with NFC. Callers that move
+// the caret after inserting must do so from the returned position by the length of the returned runes.
+func (z *Editor) composeInput(r []rune, pos CharPos) (CharPos, []rune) {
+	r = z.prepareInput(r)
+	form, ok := z.normForm()
+	if !ok || len(r) == 0 || pos.Column == 0 || !z.ValidPos(pos) ||
+		form.PropertiesString(string(r[0])).BoundaryBefore() {
+		return pos, r
+	}
+	prefix := []byte(string(z.row(pos.Line)[:pos.Column]))
+	cluster := []rune(string(prefix[max(form.LastBoundary(prefix), 0):]))
+	start := CharPos{Line: pos.Line, Column: pos.Column - len(cluster)}
+	if len(cluster) == 0 || z.isProtectedRange(CharInterval{Start: start, End: CharPos{Line: pos.Line, Column: pos.Column - 1}}) {
+		return pos, r
+	}
+	return start, z.prepareInput(append(cluster, r...))
+}
+
+// isControlChar returns true if c is a control character other than a tab or line feed.
+func (z *Editor) isControlChar(c rune) bool {
+	if c == '\t' || c == '\n' || c == z.Config.HardLF || c == z.Config.SoftLF {
+		return false
+	}
+	return unicode.IsControl(c)
+}
+
+// stripControlChars returns r without control characters other than tabs and line feeds.
+func (z *Editor) stripControlChars(r []rune) []rune {
+	return slices.DeleteFunc(slices.Clone(r), z.isControlChar)
+}
+
+// controlPicture returns the Unicode control picture glyph for the control character c, or the
+// replacement character if there is none. Caret notation like ^L is not used because each char
+// of the text must be displayed in exactly one cell.
+func controlPicture(c rune) rune {
+	switch {
+	case c < 0x20:
+		return 0x2400 + c
+	case c == 0x7f:
+		return 0x2421
+	default:
+		return unicode.ReplacementChar
+	}
+}
+
 // GetText returns the text of the whole editor as a unicode string.
 func (z *Editor) GetText() string {
 	var sb strings.Builder
 	for i := range z.Rows {
-		for j := 0; j < len(z.Rows[i])-1; j++ {
-			sb.WriteRune(z.Rows[i][j])
+		row := z.row(i)
+		for j := 0; j < len(row)-1; j++ {
+			sb.WriteRune(row[j])
 		}
-		switch z.Rows[i][len(z.Rows[i])-1] {
+		switch row[len(row)-1] {
 		case z.Config.SoftLF:
 			// do nothing
 		case z.Config.HardLF:
 			sb.WriteRune(z.Config.HardLF)
 		default:
-			sb.WriteRune(z.Rows[i][len(z.Rows[i])-1])
+			sb.WriteRune(row[len(row)-1])
 		}
 	}
 	return sb.String()
@@ -1008,8 +2124,13 @@ func (z *Editor) GetTextRange(interval CharInterval) string {
 // This method is for console mode applications and should not be used for user editing.
 // If config.MaxPrintLines is exceeded, lines are cut off at the beginning of the
 // buffer.
+//
+// In console mode (Config.ConsoleMode), the view only follows the output if it was scrolled to the
+// bottom before, so users can read earlier output while new output is printed.
 func (z *Editor) Print(s string, tags []Tag) {
 	var pos, pos2 CharPos
+	follow := z.lineOffset >= z.maxLineOffset()
+	top := z.lineOffset
 	z.MoveCaret(CaretEnd)
 	pos = z.caretPos
 	lines := strings.Split(s, "\n")
@@ -1019,12 +2140,99 @@ func (z *Editor) Print(s string, tags []Tag) {
 		z.SetCaret(z.LastPos())
 		pos2 = z.caretPos
 		if i < len(lines)-1 {
-			z.Return()
+			z.insertLineBreak()
 		}
 	}
 	if tags != nil {
 		z.Tags.Add(CharInterval{Start: pos, End: pos2}, tags...)
 	}
+	top -= z.trimPrintLines()
+	if z.Config.ConsoleMode && !follow {
+		z.SetTopLine(SafePositiveValue(top, z.maxLineOffset()))
+		return
+	}
+	z.SetTopLine(z.maxLineOffset())
+}
+
+// PrintLine prints a string followed by a newline at the end of the buffer like Print.
+func (z *Editor) PrintLine(s string, tags []Tag) {
+	z.Print(s+"\n", tags)
+}
+
+// Print_Sync is like Print but holds the editor's edit lock, so it may be called from a background
+// goroutine, e.g. one that reads the output of a process.
+func (z *Editor) Print_Sync(s string, tags []Tag) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	z.Print(s, tags)
+}
+
+// PrintLine_Sync is like PrintLine but holds the editor's edit lock, so it may be called from a
+// background goroutine.
+func (z *Editor) PrintLine_Sync(s string, tags []Tag) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	z.Print(s+"\n", tags)
+}
+
+// trimPrintLines removes whole paragraphs from the start of the text until there are no more than
+// Config.MaxPrintLines rows, or only one paragraph is left. Tags are moved up accordingly, and tags
+// within the removed rows are deleted. It returns the number of rows removed.
+func (z *Editor) trimPrintLines() int {
+	if z.Config.MaxPrintLines <= 0 || len(z.Rows) <= z.Config.MaxPrintLines {
+		return 0
+	}
+	z.materialize()
+	n, paragraphs := 0, 0
+	for len(z.Rows)-n > z.Config.MaxPrintLines {
+		end := z.FindParagraphEnd(n, z.Config.HardLF)
+		if end >= z.LastLine() {
+			break
+		}
+		n = end + 1
+		paragraphs++
+	}
+	if n == 0 {
+		return 0
+	}
+	for _, tag := range z.Tags.AllTags() {
+		interval := tag.Interval
+		if interval.End.Line < n {
+			z.Tags.Delete(tag.Tag)
+			continue
+		}
+		if interval.Start.Line < n {
+			interval.Start = CharPos{Line: n, Column: 0}
+		}
+		interval.Start.Line -= n
+		interval.End.Line -= n
+		z.Tags.Upsert(tag.Tag, interval)
+	}
+	for a := range z.anchors {
+		if a.pos.Line < n {
+			a.Release()
+		} else {
+			a.pos.Line -= n
+		}
+	}
+	removed := z.runeCount(0, n)
+	z.Rows = z.Rows[n:]
+	if z.highlightStates != nil {
+		z.highlightStates = z.highlightStates[min(paragraphs, len(z.highlightStates)):]
+	}
+	z.caretPos.Line = max(0, z.caretPos.Line-n)
+	z.markChanged()
+	z.notifyChange(Change{Removed: removed})
+	return n
+}
+
+// wrapColumn returns the column at which lines are word wrapped, which is Config.WrapColumn if it is
+// set and the number of visible columns otherwise.
+func (z *Editor) wrapColumn() int {
+	if z.Config.WrapColumn > 0 {
+		return z.Config.WrapColumn
+	}
+	return z.Columns
 }
 
 // wrapLine word wraps a line of runes according to the editor settings for soft wrapping.
@@ -1041,8 +2249,11 @@ func (z *Editor) wrapLine(r []rune) [][]rune {
 		if unicode.IsSpace(r[i]) {
 			lastGap = i
 			hasSpace = true
+		} else if i > lineStart && z.canBreakBefore(r[i-1], r[i]) {
+			lastGap = i - 1
+			hasSpace = true
 		}
-		if c >= z.Columns {
+		if c >= z.wrapColumn() {
 			if !hasSpace {
 				lastGap = i
 			}
@@ -1058,7 +2269,7 @@ func (z *Editor) wrapLine(r []rune) [][]rune {
 			b.Reset()
 			lineStart = lastGap + 1
 			hasSpace = false
-			c = 0
+			c = i - lastGap
 		}
 	}
 
@@ -1071,6 +2282,15 @@ func (z *Editor) wrapLine(r []rune) [][]rune {
 	return lines
 }
 
+// canBreakBefore returns true if Config.CanBreakBefore is set and allows a line break between
+// prev and next, false otherwise.
+func (z *Editor) canBreakBefore(prev, next rune) bool {
+	if z.Config.CanBreakBefore == nil {
+		return false
+	}
+	return z.Config.CanBreakBefore(prev, next)
+}
+
 // PARAGRAPHS
 
 // LineToPara returns the real paragraph number for a given 0-indexed row if there is one,
@@ -1090,11 +2310,11 @@ func (z *Editor) LineToPara(row int) (int, bool) {
 	}
 	c := 0
 	for i := 0; i < row; i++ {
-		if z.RuneAt_Sync(i, z.LastColumn(i)) == z.Config.HardLF {
+		if z.row(i)[z.LastColumn(i)] == z.Config.HardLF {
 			c++
 		}
 	}
-	return c + 1, z.RuneAt_Sync(row-1, z.LastColumn(row-1)) == z.Config.HardLF
+	return c + 1, z.row(row - 1)[z.LastColumn(row-1)] == z.Config.HardLF
 }
 
 // ParaToLine returns the 0-indexed line number at which the given 1-index
@@ -1104,7 +2324,7 @@ func (z *Editor) ParaToLine(paraNum int) (int, bool) {
 	n := 0
 	c := 0
 	for i := range z.Rows {
-		if z.Rows[i][z.LastColumn(i)] == z.Config.HardLF {
+		if z.row(i)[z.LastColumn(i)] == z.Config.HardLF {
 			n = i + 1
 			c++
 		}
@@ -1118,34 +2338,193 @@ func (z *Editor) ParaToLine(paraNum int) (int, bool) {
 // ParaCount counts the number of paragraphs, which is equivalent to the number of lines
 // ending in HardLF + 1.
 func (z *Editor) ParaCount() int {
+	if z.paged != nil {
+		return len(z.Rows) // rows of a custom buffer are not wrapped
+	}
 	c := 0
 	for i := range z.Rows {
 		if z.Rows[i][z.LastColumn(i)] == z.Config.HardLF {
 			c++
 		}
 	}
-	return c
+	return c
+}
+
+// CountLines returns the number of rows for which pred returns true. The rows are passed to pred
+// directly and must not be modified or retained by it, and pred must not modify the editor.
+func (z *Editor) CountLines(pred func(line []rune) bool) int {
+	n := 0
+	for i := range z.Rows {
+		if pred(z.row(i)) {
+			n++
+		}
+	}
+	return n
+}
+
+// defaultTabWidth is the tab width used if Config.TabWidth is not set.
+const defaultTabWidth = 4
+
+// LineIndentation returns the width of the leading whitespace of the given row, counting tabs
+// as TabWidth spaces, and true if the row contains anything else than whitespace. If the row
+// is blank or does not exist, false is returned.
+func (z *Editor) LineIndentation(row int) (int, bool) {
+	if row < 0 || row > z.LastLine() {
+		return 0, false
+	}
+	tabWidth := z.tabWidth()
+	n := 0
+	for i := 0; i < z.LastColumn(row); i++ {
+		switch z.row(row)[i] {
+		case ' ':
+			n++
+		case '\t':
+			n += tabWidth - n%tabWidth
+		default:
+			return n, true
+		}
+	}
+	return n, false
+}
+
+// firstNonSpaceColumn returns the first column of a row that is not a space or tab, or the last column
+// if there is none.
+func (z *Editor) firstNonSpaceColumn(row int) int {
+	for i := 0; i < z.LastColumn(row); i++ {
+		if c := z.row(row)[i]; c != ' ' && c != '\t' {
+			return i
+		}
+	}
+	return z.LastColumn(row)
+}
+
+// GotoBlockStart moves the caret to the nearest paragraph above the caret paragraph that is
+// less indented than it, or to the first line if there is none. Blank lines are ignored.
+func (z *Editor) GotoBlockStart() {
+	row := z.FindParagraphStart(z.caretPos.Line, z.Config.HardLF)
+	indent, ok := z.LineIndentation(row)
+	target := 0
+	for row > 0 {
+		row = z.FindParagraphStart(row-1, z.Config.HardLF)
+		n, nonBlank := z.LineIndentation(row)
+		if !nonBlank {
+			continue
+		}
+		if !ok {
+			indent, ok = n, true
+			continue
+		}
+		if n < indent {
+			target = row
+			break
+		}
+	}
+	z.SetCaret(CharPos{Line: target, Column: z.firstNonSpaceColumn(target)})
+	z.scrollToCaret()
+}
+
+// GotoBlockEnd moves the caret to the nearest paragraph below the caret paragraph that is
+// less indented than it, or to the last line if there is none. Blank lines are ignored.
+func (z *Editor) GotoBlockEnd() {
+	row := z.FindParagraphStart(z.caretPos.Line, z.Config.HardLF)
+	indent, ok := z.LineIndentation(row)
+	target := z.LastLine()
+	for {
+		row = z.FindParagraphEnd(row, z.Config.HardLF) + 1
+		if row > z.LastLine() {
+			break
+		}
+		n, nonBlank := z.LineIndentation(row)
+		if !nonBlank {
+			continue
+		}
+		if !ok {
+			indent, ok = n, true
+			continue
+		}
+		if n < indent {
+			target = row
+			break
+		}
+	}
+	z.SetCaret(CharPos{Line: target, Column: z.firstNonSpaceColumn(target)})
+	z.scrollToCaret()
+}
+
+// fillVirtualSpace inserts spaces up to the caret if the caret is in virtual space after the end of
+// a paragraph, and moves the caret to the end of the inserted spaces.
+func (z *Editor) fillVirtualSpace() {
+	n := z.virtualSpace
+	if n <= 0 {
+		return
+	}
+	z.virtualSpace = 0
+	z.Insert([]rune(strings.Repeat(" ", n)), z.caretPos)
+	for range n {
+		z.MoveCaret(CaretRight)
+	}
+}
+
+// endsParagraph returns true if the given row ends in a hard line feed, i.e. the caret may move into
+// virtual space after it. Rows ending in a soft line feed continue on the next row.
+func (z *Editor) endsParagraph(line int) bool {
+	row := z.row(line)
+	return len(row) > 0 && row[len(row)-1] == z.Config.HardLF
+}
+
+// KEY HANDLING
+
+func (z *Editor) TypedRune(r rune) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	if z.Config.ReadOnly {
+		return
+	}
+	z.markInteraction()
+	// normalization may turn the rune into several runes, or stripping control chars into none
+	rs := z.prepareInput([]rune{r})
+	if len(rs) == 0 {
+		return
+	}
+	if z.typeIntoBlock(rs) || z.typeAtCarets(rs) {
+		return
+	}
+	if !z.maybeDeleteSelection() {
+		return
+	}
+	z.fillVirtualSpace()
+	if len(rs) == 1 && z.maybeAutoPair(rs[0]) {
+		return
+	}
+	// the inserted runes may be composed with the grapheme cluster before the caret
+	_, composed := z.composeInput(rs, z.caretPos)
+	z.Insert(rs, z.caretPos)
+	for range composed {
+		z.MoveCaret(CaretRight)
+	}
+	z.maybeReindentClosing(rs[len(rs)-1])
 }
 
-// KEY HANDLING
-
-func (z *Editor) TypedRune(r rune) {
-	z.lastInteraction = time.Now()
-	z.Insert([]rune{r}, z.caretPos)
-	z.MoveCaret(CaretRight)
+// AcceptsTab returns true, so that the tab key is passed to the editor instead of moving the focus.
+func (z *Editor) AcceptsTab() bool {
+	return true
 }
 
 func (z *Editor) TypedKey(evt *fyne.KeyEvent) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	if handler, ok := z.keyHandlers[evt.Name]; ok {
-		z.lastInteraction = time.Now()
+		z.markInteraction()
 		handler(z)
 	}
 }
 
 func (z *Editor) TypedShortcut(s fyne.Shortcut) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	if ks, ok := s.(fyne.KeyboardShortcut); ok {
 		if handler, ok := z.handlers[GetKeyboardShortcutKey(ks)]; ok {
-			z.lastInteraction = time.Now()
+			z.markInteraction()
 			handler(z)
 		}
 	}
@@ -1189,10 +2568,10 @@ func (z *Editor) addDefaultShortcuts() {
 		z.MoveCaret(CaretRight)
 	})
 	z.AddKeyHandler(fyne.KeyHome, func(z *Editor) {
-		z.MoveCaret(CaretHome)
+		z.MoveCaret(CaretLineStart)
 	})
 	z.AddKeyHandler(fyne.KeyEnd, func(z *Editor) {
-		z.MoveCaret(CaretEnd)
+		z.MoveCaret(CaretLineEnd)
 	})
 	z.AddKeyHandler(fyne.KeyPageDown, func(z *Editor) {
 		z.MoveCaret(CaretHalfPageDown)
@@ -1209,6 +2588,9 @@ func (z *Editor) addDefaultShortcuts() {
 	z.AddKeyHandler(fyne.KeyReturn, func(z *Editor) {
 		z.Return()
 	})
+	z.AddKeyHandler(fyne.KeyTab, func(z *Editor) {
+		z.Tab()
+	})
 	// shortcuts
 	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyPageDown, Modifier: fyne.KeyModifierControl},
 		func(z *Editor) {
@@ -1218,10 +2600,66 @@ func (z *Editor) addDefaultShortcuts() {
 		func(z *Editor) {
 			z.MoveCaret(CaretPageUp)
 		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyHome, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.MoveCaret(CaretHome)
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyEnd, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.MoveCaret(CaretEnd)
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyUp, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.MoveCaret(CaretParagraphStart)
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyDown, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.MoveCaret(CaretParagraphEnd)
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyLeft, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.MoveCaret(CaretWordLeft)
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyRight, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.MoveCaret(CaretWordRight)
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyBackspace, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.DeleteWordLeft()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyDelete, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.DeleteWordRight()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyTab, Modifier: fyne.KeyModifierShift},
+		func(z *Editor) {
+			z.Outdent()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyUp, Modifier: fyne.KeyModifierAlt},
+		func(z *Editor) {
+			z.MoveLineUp()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyDown, Modifier: fyne.KeyModifierAlt},
+		func(z *Editor) {
+			z.MoveLineDown()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyD, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.DuplicateLine()
+		})
 	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyX, Modifier: fyne.KeyModifierControl},
 		func(z *Editor) {
 			z.Cut()
 		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyC, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.Copy()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyV, Modifier: fyne.KeyModifierControl},
+		func(z *Editor) {
+			z.Paste()
+		})
 	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.Key1, Modifier: fyne.KeyModifierAlt},
 		func(z *Editor) {
 			z.SetMark(1)
@@ -1266,6 +2704,16 @@ func (z *Editor) addDefaultShortcuts() {
 		func(z *Editor) {
 			z.SelectAll()
 		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyRight,
+		Modifier: fyne.KeyModifierAlt | fyne.KeyModifierShift},
+		func(z *Editor) {
+			z.ExpandSelection()
+		})
+	z.AddShortcutHandler(&desktop.CustomShortcut{KeyName: fyne.KeyLeft,
+		Modifier: fyne.KeyModifierAlt | fyne.KeyModifierShift},
+		func(z *Editor) {
+			z.ShrinkSelection()
+		})
 }
 
 // AddEmacsShortcuts adds some (very basic) Emacs shortcuts but some with Super key as modifier instead of Ctrl
@@ -1309,6 +2757,9 @@ func (z *Editor) AddEmacsShortcuts() {
 // LAYOUT UPDATING
 
 func (z *Editor) Refresh() {
+	if z.isClosed() {
+		return
+	}
 	z.mutex.RLock()
 	last := z.lastRefreshed
 	fn := z.refresher
@@ -1332,163 +2783,511 @@ func (z *Editor) Refresh() {
 	if fn != nil {
 		return
 	}
-	go func() {
-		time.Sleep(interval)
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	if z.refreshTimer != nil {
+		return
+	}
+	z.refreshTimer = time.AfterFunc(interval, func() {
+		defer z.recoverIfClosed()
+		z.mutex.Lock()
+		z.refreshTimer = nil
+		z.mutex.Unlock()
+		z.editMutex.Lock()
+		defer z.editMutex.Unlock()
+		z.closeMutex.RLock()
+		defer z.closeMutex.RUnlock()
 		z.Refresh()
-	}()
+	})
 }
 
 func (z *Editor) refreshProc() {
 	defer func() {
-		z.lastInteraction = time.Now()
+		z.markInteraction()
 		z.maybeDrawCaret()
 	}()
+	if z.themeChanged() {
+		z.applyTheme()
+	}
+	if !z.Config.LineWrap && z.MaxLineLength() > z.Columns {
+		// the caret or a shortened line may have left the column offset beyond the scroll range
+		z.columnOffset = min(z.columnOffset, z.maxColumnOffset())
+	}
+	z.computeDisplayLines()
+	stale := z.staleRows()
 outer:
-	for i := range z.Lines {
-		if i+z.lineOffset >= len(z.Rows) {
+	for _, i := range stale {
+		if vl := z.displayVirtual[i]; vl != nil {
+			style := vl.style.ToTextGridStyle()
+			for j := range z.Columns {
+				z.grid.Rows[i].Cells[j].Rune = ' '
+				if j+z.columnOffset < len(vl.text) {
+					z.grid.Rows[i].Cells[j].Rune = vl.text[j+z.columnOffset]
+				}
+				z.grid.Rows[i].Cells[j].Style = style
+			}
+			continue outer
+		}
+		row := z.displayLines[i]
+		if row < 0 || row >= len(z.Rows) {
 			z.grid.Rows[i].Style = nil
 			for j := range z.Columns {
 				z.grid.Rows[i].Cells[j].Rune = ' '
 				z.grid.Rows[i].Cells[j].Style = nil
 			}
+			if eob := z.Config.EndOfBufferChar; eob != 0 && eob != ' ' && z.columnOffset == 0 && z.Columns > 0 {
+				z.grid.Rows[i].Cells[0].Rune = eob
+				z.grid.Rows[i].Cells[0].Style = z.lineNumberStyle.ToTextGridStyle()
+			}
 			continue outer
 		}
 	inner:
 		for j := range z.Columns {
-			if j+z.columnOffset >= len(z.Rows[i+z.lineOffset]) {
+			if j+z.columnOffset >= len(z.row(row)) {
 				z.grid.Rows[i].Cells[j].Rune = ' '
 				z.grid.Rows[i].Cells[j].Style = nil
 				continue inner
 			}
-			z.grid.Rows[i].Cells[j].Rune = z.Rows[i+z.lineOffset][j+z.columnOffset]
+			z.grid.Rows[i].Cells[j].Rune = z.row(row)[j+z.columnOffset]
 			z.grid.Rows[i].Cells[j].Style = nil
+			if z.Config.ControlCharPolicy == ControlPicture && z.isControlChar(z.grid.Rows[i].Cells[j].Rune) {
+				z.grid.Rows[i].Cells[j].Rune = controlPicture(z.grid.Rows[i].Cells[j].Rune)
+				z.grid.Rows[i].Cells[j].Style = z.lineNumberStyle.ToTextGridStyle()
+			}
 		}
 	}
 
 	if z.Config.ShowLineNumbers {
-		z.lineNumberGrid.Hidden = false
+		z.lineNumberView().Show()
 		// add line numbers if necessary
 		ll := strconv.Itoa(max(z.lineNumberLen(), 2))
 		fmtStr := " %" + ll + "d "
 		paraLineNo := z.Config.ParagraphLineNumbers
 		showLineNo := !paraLineNo
+		caretLino := z.caretPos.Line + 1
+		if paraLineNo {
+			caretLino, _ = z.LineToPara(z.caretPos.Line)
+		}
+		// LineToPara counts from 1
+		startDelta := z.Config.LineNumberStart - 1
+		caretLino += startDelta
 		for i := 0; i < z.Lines; i++ {
 			var s []rune
+			row := z.displayLines[i]
+			lino := row + 1
 			if paraLineNo {
-				var lino int
-				lino, showLineNo = z.LineToPara(z.lineOffset + i)
-				s = []rune(fmt.Sprintf(fmtStr, lino))
+				lino, showLineNo = z.LineToPara(row)
+			} else {
+				showLineNo = true
+			}
+			if z.displayVirtual[i] != nil || row < 0 {
+				showLineNo = false
+			}
+			lino += startDelta
+			if z.Config.LineNumberFormat != nil {
+				s = []rune(z.Config.LineNumberFormat(lino, caretLino))
 			} else {
-				s = []rune(fmt.Sprintf(fmtStr, z.lineOffset+i+1))
+				s = []rune(fmt.Sprintf(fmtStr, lino))
+			}
+			if !showLineNo || row > z.LastLine() {
+				s = []rune(strings.Repeat(" ", len(s)))
 			}
+			if string(s) == z.shownLineNumbers[i] {
+				continue
+			}
+			z.shownLineNumbers[i] = string(s)
 			for j := 0; j < len(s); j++ {
-				if showLineNo && z.lineOffset+i <= z.LastLine() {
-					z.lineNumberGrid.SetCell(i, j, widget.TextGridCell{Rune: s[j],
-						Style: z.lineNumberStyle.ToTextGridStyle()})
-				} else {
-					z.lineNumberGrid.SetCell(i, j, widget.TextGridCell{Rune: ' ',
-						Style: z.lineNumberStyle.ToTextGridStyle()})
-				}
+				z.setLineNumberCell(i, j, widget.TextGridCell{Rune: s[j],
+					Style: z.lineNumberStyle.ToTextGridStyle()})
 			}
+			// clear what is left over from a previous, longer line number
+			for j := len(s); j < len(z.lineNumberGrid.Rows[i].Cells); j++ {
+				z.setLineNumberCell(i, j, widget.TextGridCell{Rune: ' ',
+					Style: z.lineNumberStyle.ToTextGridStyle()})
+			}
+			z.lineNumberRows.Objects[i].Refresh()
 		}
 	}
 
-	stylers := z.Styles.Stylers()
-	if stylers != nil {
-		for i := len(stylers) - 1; i >= 0; i-- {
-			tags, ok := z.Tags.TagsByName(stylers[i].TagName)
-			if !ok {
-				continue
-			}
-			loop := tags.Iter()
-			for {
-				tag, ok := loop.Next()
-				if !ok {
-					break
-				}
-				interval, ok := z.Tags.Lookup(tag)
-				if !ok {
-					continue
-				}
-				z.maybeStyleRange(tag, interval, stylers[i].StyleFunc, stylers[i].DrawFullLine)
+	// only the stale rows are styled, the others still display what they displayed before
+	lines := z.maskLines(stale)
+	if z.Config.HighlightTrailingWhitespace {
+		z.highlightTrailingWhitespace(z.grid, lines, z.columnOffset)
+	}
+	z.applyRuleStylers(z.grid, lines, z.columnOffset)
+	z.applyStylers(z.grid, lines, z.columnOffset)
+	z.maybeDrawPlaceHolder()
+	z.adjustScroll()
+	z.refreshGutterMarkers()
+	z.refreshGridRows(stale)
+	z.maybeHandleViewportChange()
+}
+
+// maybeDrawPlaceHolder draws Config.PlaceHolder into the display grid if the text is empty and the
+// editor does not have the focus. The placeholder is only displayed and not part of the text.
+func (z *Editor) maybeDrawPlaceHolder() {
+	if !z.showsPlaceHolder() {
+		return
+	}
+	style := Style{FGColor: z.lineNumberStyle.FGColor, BGColor: z.defaultStyle.BGColor}.ToTextGridStyle()
+	for i, line := range strings.Split(z.Config.PlaceHolder, "\n") {
+		if i >= z.Lines {
+			break
+		}
+		for j, r := range []rune(line) {
+			if j >= z.Columns {
+				break
 			}
+			z.grid.Rows[i].Cells[j].Rune = r
+			z.grid.Rows[i].Cells[j].Style = style
 		}
 	}
-	z.adjustScroll()
-	z.lineNumberGrid.Refresh()
-	z.grid.Refresh()
+}
+
+// showsPlaceHolder returns true if the placeholder is displayed instead of the empty text.
+func (z *Editor) showsPlaceHolder() bool {
+	return z.Config.PlaceHolder != "" && !z.hasFocus && len(z.Rows) <= 1 && (len(z.Rows) == 0 || len(z.row(0)) <= 1)
+}
+
+// maybeHandleViewportChange calls the Config.OnViewportChange callback after Config.ViewportChangeDelay
+// if the line or column offset has changed since the last call. Changes in short succession only result
+// in one call.
+func (z *Editor) maybeHandleViewportChange() {
+	if z.lineOffset == z.lastLineOffset && z.columnOffset == z.lastColumnOffset {
+		return
+	}
+	z.lastLineOffset = z.lineOffset
+	z.lastColumnOffset = z.columnOffset
+	if handler, ok := z.eventHandlers[ScrollEvent]; ok && handler != nil {
+		handler(ScrollEvent, z)
+	}
+	if z.Config.OnViewportChange == nil {
+		return
+	}
+	if z.viewportTimer != nil {
+		z.viewportTimer.Stop()
+	}
+	// The viewport is computed now rather than in the timer's goroutine, which must not access the
+	// rows. If it changes again before the delay has passed, the timer is replaced.
+	fn := z.Config.OnViewportChange
+	viewport := z.currentViewport()
+	z.viewportTimer = time.AfterFunc(z.Config.ViewportChangeDelay, func() {
+		defer z.recoverIfClosed()
+		if !z.isClosed() {
+			fn(viewport)
+		}
+	})
 }
 
 // curreentViewport is the char interval that is currently displayed
 func (z *Editor) currentViewport() CharInterval {
-	endLine := min(len(z.Rows)-1, z.lineOffset+z.Lines-1)
-	endColumn := len(z.Rows[endLine]) - 1
-	return CharInterval{Start: CharPos{Line: z.lineOffset, Column: 0},
+	return z.linesInterval(z.lineOffset, z.Lines)
+}
+
+// linesInterval returns the char interval of n lines starting at startLine, clamped to the text.
+func (z *Editor) linesInterval(startLine, n int) CharInterval {
+	endLine := min(len(z.Rows)-1, startLine+n-1)
+	endColumn := len(z.row(endLine)) - 1
+	return CharInterval{Start: CharPos{Line: startLine, Column: 0},
 		End: CharPos{Line: endLine, Column: endColumn}}
 }
 
+// RenderSlice returns a new text grid displaying count lines starting at startLine, styled by the same
+// stylers as the editor. The main display, scroll position, and caret are not affected. This can be used
+// for displaying a preview of some part of the text in a popup.
+func (z *Editor) RenderSlice(startLine, count int) *widget.TextGrid {
+	grid := widget.NewTextGrid()
+	startLine = SafePositiveValue(startLine, z.LastLine())
+	count = SafePositiveValue(count, z.LastLine()-startLine+1)
+	if count == 0 {
+		return grid
+	}
+	grid.Rows = make([]widget.TextGridRow, count)
+	for i := range grid.Rows {
+		row := z.row(startLine + i)
+		grid.Rows[i].Cells = make([]widget.TextGridCell, len(row))
+		for j := range row {
+			grid.Rows[i].Cells[j].Rune = row[j]
+		}
+	}
+	lines := make([]int, count)
+	for i := range lines {
+		lines[i] = startLine + i
+	}
+	z.applyStylers(grid, lines, 0)
+	return grid
+}
+
 // CARET HANDLING
 
 // drawCaret draws the text cursor if necessary.
 func (z *Editor) maybeDrawCaret() bool {
-	if !z.Config.DrawCaret {
+	z.caretRectsUsed = 0
+	defer z.hideUnusedCaretRects()
+	if !z.Config.DrawCaret || z.isClosed() || z.showsPlaceHolder() {
 		return false
 	}
-	line := z.caretPos.Line - z.lineOffset
-	if line < 0 || line > z.Lines-1 {
+	drawn := z.drawCaretAt(z.caretPos, z.virtualSpace)
+	for _, a := range z.carets {
+		if pos, ok := a.Pos(); ok && z.drawCaretAt(pos, 0) {
+			drawn = true
+		}
+	}
+	if drawn {
+		z.refreshGridRows(z.caretGridRows)
+	}
+	return drawn
+}
+
+// drawCaretRect shows an overlay rectangle for a bar or underline caret in the given grid cell.
+func (z *Editor) drawCaretRect(row, col int) {
+	if z.caretRectsUsed >= len(z.caretRects) {
+		rect := canvas.NewRectangle(theme.ForegroundColor())
+		z.caretRects = append(z.caretRects, rect)
+		z.caretOverlay.Add(rect)
+	}
+	rect := z.caretRects[z.caretRectsUsed]
+	z.caretRectsUsed++
+	cellWidth := math32.Round(z.charSize.Width)
+	cellHeight := math32.Round(z.charSize.Height)
+	thickness := max(1, theme.InputBorderSize())
+	pos := z.gridView().Position().Add(fyne.Position{X: float32(col) * cellWidth, Y: float32(row) * z.RowHeight()})
+	if z.Config.CaretShape == CaretUnderline {
+		rect.Move(pos.Add(fyne.Position{X: 0, Y: cellHeight - thickness}))
+		rect.Resize(fyne.Size{Width: cellWidth, Height: thickness})
+	} else {
+		rect.Move(pos)
+		rect.Resize(fyne.Size{Width: thickness, Height: cellHeight})
+	}
+	rect.FillColor = theme.ForegroundColor()
+	rect.Show()
+	rect.Refresh()
+}
+
+// hideUnusedCaretRects hides the overlay rectangles not used for drawing carets in the last call of
+// maybeDrawCaret.
+func (z *Editor) hideUnusedCaretRects() {
+	for i := z.caretRectsUsed; i < len(z.caretRects); i++ {
+		if z.caretRects[i].Visible() {
+			z.caretRects[i].Hide()
+		}
+	}
+}
+
+// drawCaretAt sets the style of the grid cell at the given position and virtual space to the caret
+// style. It returns false if the position is not displayed.
+func (z *Editor) drawCaretAt(pos CharPos, virtualSpace int) bool {
+	line, ok := z.gridRowOf(pos.Line)
+	if !ok {
 		return false
 	}
 	line = SafePositiveValue(line, len(z.grid.Rows)-1)
-	col := z.caretPos.Column - z.columnOffset
+	col := pos.Column + virtualSpace - z.columnOffset
 	if col > z.Columns-1 {
 		return false
 	}
 	col = SafePositiveValue(col, len(z.grid.Rows[line].Cells)-1)
+	if z.Config.CaretShape != CaretBlock {
+		if atomic.LoadUint32(&z.caretState) == 2 {
+			z.drawCaretRect(line, col)
+		}
+		return true
+	}
+	// the row must be rendered again at the next refresh to remove the caret
+	if !slices.Contains(z.caretGridRows, line) {
+		z.caretGridRows = append(z.caretGridRows, line)
+	}
 	switch atomic.LoadUint32(&z.caretState) {
 	case 2:
 		z.grid.Rows[line].Cells[col].Style = z.invertedDefaultStyle.ToTextGridStyle()
 	default:
 		z.grid.Rows[line].Cells[col].Style = z.defaultStyle.ToTextGridStyle()
 	}
-	z.grid.Refresh()
 	return true
 }
 
-// BlinkCursor starts blinking the cursor or stops the cursor from blinking.
+// BlinkCursor starts blinking the cursor or stops the cursor from blinking. If Config.CaretBlink
+// is false, the caret is always drawn steadily.
 func (z *Editor) BlinkCaret(on bool) {
-	if !on {
-		z.caretBlinkCancel()
-		atomic.StoreUint32(&z.hasCaretBlinking, 0)
+	atomic.StoreUint32(&z.caretBlinkPaused, 0)
+	if !on || !z.Config.CaretBlink {
+		z.stopCaretBlinking()
 		atomic.StoreUint32(&z.caretState, 2)
 		z.maybeDrawCaret()
 		return
 	}
-	atomic.StoreUint32(&z.hasCaretBlinking, 1)
+	if z.isClosed() {
+		return
+	}
+	// Cancel any running blink loop and start the new one under the lock, so exactly one loop is
+	// active. The generation lets a cancelled loop that is still running recognize it is stale.
+	z.caretBlinkMutex.Lock()
+	z.caretBlinkCancel()
+	z.caretBlinkGen++
+	gen := z.caretBlinkGen
 	ctx, cancel := context.WithCancel(context.Background())
 	z.caretBlinkCancel = cancel
+	atomic.StoreUint32(&z.hasCaretBlinking, 1)
+	z.caretBlinkMutex.Unlock()
 	go func(ctx context.Context, z *Editor) {
+		defer z.recoverIfClosed()
 		var oddTick bool
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			default:
-				if oddTick && time.Since(z.lastInteraction) > z.Config.CaretBlinkDelay {
-					atomic.StoreUint32(&z.caretState, 1)
-					oddTick = false
-					z.maybeDrawCaret()
-					time.Sleep(z.Config.CaretOffDuration)
+				if z.isClosed() {
+					return
+				}
+				if !z.Config.CaretBlink {
+					// blinking was disabled in the config while running, leave a steady caret
+					if z.setBlinkState(gen, 2) {
+						atomic.StoreUint32(&z.hasCaretBlinking, 0)
+						z.drawCaretSync()
+					}
+					return
+				}
+				onDuration, offDuration := z.caretBlinkDurations()
+				var wait time.Duration
+				var state uint32
+				if oddTick && z.sinceInteraction() > z.Config.CaretBlinkDelay {
+					state, oddTick, wait = 1, false, offDuration
 				} else {
-					atomic.StoreUint32(&z.caretState, 2)
-					oddTick = true
-					z.maybeDrawCaret()
-					time.Sleep(z.Config.CaretOnDuration)
+					state, oddTick, wait = 2, true, onDuration
+				}
+				if !z.setBlinkState(gen, state) {
+					return
+				}
+				z.drawCaretSync()
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
 				}
 			}
 		}
 	}(ctx, z)
 }
 
+// setBlinkState sets the caret state on behalf of the blink loop with the given generation. It
+// returns false without changing the state if another loop has been started or blinking was
+// stopped since, in which case the calling loop must exit.
+func (z *Editor) setBlinkState(gen uint64, state uint32) bool {
+	z.caretBlinkMutex.Lock()
+	defer z.caretBlinkMutex.Unlock()
+	if gen != z.caretBlinkGen {
+		return false
+	}
+	atomic.StoreUint32(&z.caretState, state)
+	return true
+}
+
+// stopCaretBlinking cancels the running blink loop, if any.
+func (z *Editor) stopCaretBlinking() {
+	z.caretBlinkMutex.Lock()
+	defer z.caretBlinkMutex.Unlock()
+	z.caretBlinkCancel()
+	z.caretBlinkGen++
+	atomic.StoreUint32(&z.hasCaretBlinking, 0)
+}
+
+// pauseCaretBlinking stops the running blink loop, if any, until resumeCaretBlinking is called.
+func (z *Editor) pauseCaretBlinking() {
+	if atomic.LoadUint32(&z.hasCaretBlinking) == 0 {
+		return
+	}
+	atomic.StoreUint32(&z.caretBlinkPaused, 1)
+	z.stopCaretBlinking()
+}
+
+// resumeCaretBlinking restarts caret blinking if it was stopped by pauseCaretBlinking.
+func (z *Editor) resumeCaretBlinking() {
+	if atomic.CompareAndSwapUint32(&z.caretBlinkPaused, 1, 0) {
+		z.BlinkCaret(true)
+	}
+}
+
+// caretBlinkDurations returns how long a blinking caret is shown and how long it is hidden.
+// Config.CaretBlinkRate overrides the separate on and off durations if it is set.
+func (z *Editor) caretBlinkDurations() (on, off time.Duration) {
+	if z.Config.CaretBlinkRate > 0 {
+		return z.Config.CaretBlinkRate, z.Config.CaretBlinkRate
+	}
+	return z.Config.CaretOnDuration, z.Config.CaretOffDuration
+}
+
+// Close stops the editor's background goroutines, i.e. caret blinking, pending refreshes, and
+// viewport change notifications. The editor is no longer refreshed after it has been closed; if a
+// background goroutine is drawing the editor, Close waits until it has finished. Close must be
+// called explicitly when the editor is no longer needed. Fyne destroys the renderer of a widget
+// that has not been displayed for a while, which only pauses caret blinking.
+func (z *Editor) Close() {
+	if !atomic.CompareAndSwapUint32(&z.closed, 0, 1) {
+		return
+	}
+	z.stopCaretBlinking()
+	z.mutex.Lock()
+	if z.refreshTimer != nil {
+		z.refreshTimer.Stop()
+	}
+	z.mutex.Unlock()
+	if z.viewportTimer != nil {
+		z.viewportTimer.Stop()
+	}
+	if z.hoverTimer != nil {
+		z.hoverTimer.Stop()
+	}
+	z.closeMutex.Lock()
+	defer z.closeMutex.Unlock()
+}
+
+// Do calls fn while holding the editor's edit lock, which serializes it with user input, refreshes
+// and caret drawing. Any modification of the editor from a goroutine other than the one on which
+// Fyne delivers input events must be made in a function passed to Do. Do must not be called from
+// within fn or from within editor event handlers or keyboard shortcut handlers, as this deadlocks.
+func (z *Editor) Do(fn func()) {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	fn()
+}
+
+// drawCaretSync draws the caret while holding the edit lock.
+func (z *Editor) drawCaretSync() {
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
+	z.closeMutex.RLock()
+	defer z.closeMutex.RUnlock()
+	if z.isClosed() {
+		return
+	}
+	z.maybeDrawCaret()
+}
+
+// markInteraction records the time of a user interaction, which delays caret blinking.
+func (z *Editor) markInteraction() {
+	atomic.StoreInt64(&z.lastInteraction, time.Now().UnixNano())
+}
+
+// sinceInteraction returns the time elapsed since the last user interaction.
+func (z *Editor) sinceInteraction() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&z.lastInteraction)))
+}
+
+// isClosed returns true if the editor has been closed, in which case background goroutines must
+// no longer draw or refresh.
+func (z *Editor) isClosed() bool {
+	return atomic.LoadUint32(&z.closed) > 0
+}
+
+// recoverIfClosed is deferred in background goroutines and recovers from panics that occur
+// because the editor was closed while the goroutine was running. Other panics are passed on.
+func (z *Editor) recoverIfClosed() {
+	if r := recover(); r != nil && !z.isClosed() {
+		panic(r)
+	}
+}
+
 // HasBlinkingCaret returns true if the input cursor is blinking, false otherwise.
 // use BlinkCursor to switch blinking on or off.
 func (z *Editor) HasBlinkingCaret() bool {
@@ -1498,8 +3297,8 @@ func (z *Editor) HasBlinkingCaret() bool {
 // CaretOff switches the caret off temporarily. It returns true was blinking.
 func (z *Editor) CaretOff() bool {
 	blinking := z.HasBlinkingCaret()
-	z.caretBlinkCancel()
-	z.caretState = 0
+	z.stopCaretBlinking()
+	atomic.StoreUint32(&z.caretState, 0)
 	z.Config.DrawCaret = false
 	z.Refresh()
 	return blinking
@@ -1508,7 +3307,7 @@ func (z *Editor) CaretOff() bool {
 // CaretOn switches the caret on again after it has been switched off.
 func (z *Editor) CaretOn(blinking bool) {
 	z.Config.DrawCaret = true
-	z.caretState = 2
+	atomic.StoreUint32(&z.caretState, 2)
 	z.BlinkCaret(blinking)
 	z.Refresh()
 }
@@ -1542,6 +3341,7 @@ func (z *Editor) GetCaret() CharPos {
 // and caret events but without scrolling or refreshing the display.
 func (z *Editor) SetCaret(pos CharPos) {
 	pos = MinPos(pos, z.LastPos())
+	z.virtualSpace = 0
 	// handle caret leave event
 	z.handleCaretEvent(CaretLeaveEvent, z.caretPos, pos)
 
@@ -1580,11 +3380,31 @@ func (z *Editor) maybeHandleWordChangeEvent(pos CharPos) {
 	}
 }
 
+// RefreshCurrentWord determines the word at the caret anew and calls the WordChangeEvent handler
+// if it has changed. This can be used to keep CurrentWord up to date after the text has been
+// changed programmatically without moving the caret.
+func (z *Editor) RefreshCurrentWord() {
+	word, _ := z.getWordAt(z.caretPos)
+	if word == z.currentWord {
+		return
+	}
+	z.currentWord = word
+	if handler, ok := z.eventHandlers[WordChangeEvent]; ok && handler != nil {
+		handler(WordChangeEvent, z)
+	}
+}
+
 // CurrentWord returns the current word under the caret, "" is there is none.
 func (z *Editor) CurrentWord() string {
 	return z.currentWord
 }
 
+// TagsAtCaret returns all tags whose interval contains the caret position, together with their intervals,
+// sorted like the result of TagsAt.
+func (z *Editor) TagsAtCaret() []TagWithInterval {
+	return z.TagsAt(z.caretPos)
+}
+
 func (z *Editor) maybeHighlightParen() {
 	z.Tags.DeleteByName(z.Config.HighlightTag.Name())
 	z.Tags.Delete(z.Config.ParenErrorTag)
@@ -1602,27 +3422,38 @@ func (z *Editor) maybeHighlightParen() {
 	if !(IsRightParen(r) || IsQuotationMark(r)) {
 		return
 	}
-	current, ok := z.PrevPos(pos)
+	if !z.shouldMatchBracketAt(pos) {
+		return
+	}
+	lpos, ok := z.findLeftMatch(pos, r)
 	if !ok {
 		z.MarkErrorParen(CharInterval{Start: pos, End: pos})
 		return
 	}
-	var match rune
-	switch r {
-	case ')':
-		match = '('
-	case ']':
-		match = '['
-	case '}':
-		match = '{'
-	default:
-		match = r
+	if z.Config.HighlightParenRange {
+		z.Highlight(CharInterval{Start: lpos, End: pos})
+		return
+	}
+	z.Highlight(CharInterval{Start: pos, End: pos})
+	z.Highlight(CharInterval{Start: lpos, End: lpos})
+}
+
+// findLeftMatch returns the position of the left paren matching the right paren r at pos, or of
+// the previous quotation mark of the same kind if r is a quotation mark.
+func (z *Editor) findLeftMatch(pos CharPos, r rune) (CharPos, bool) {
+	current, ok := z.PrevPos(pos)
+	if !ok {
+		return CharPos{}, false
 	}
+	match := matchingBracket(r)
 	openParens := 0
 	if IsRightParen(r) {
 		openParens = 1
 	}
-	lpos, ok := z.FindRune(current, true, func(c rune) bool {
+	return z.findRuneAt(current, true, func(c rune, p CharPos) bool {
+		if !z.shouldMatchBracketAt(p) {
+			return false
+		}
 		if IsRightParen(c) {
 			openParens++
 		} else if IsLeftParen(c) {
@@ -1630,27 +3461,32 @@ func (z *Editor) maybeHighlightParen() {
 		}
 		return c == match && openParens == 0
 	})
-	if !ok {
-		z.MarkErrorParen(CharInterval{Start: pos, End: pos})
-		return
-	}
-	if z.Config.HighlightParenRange {
-		z.Highlight(CharInterval{Start: lpos, End: pos})
-		return
+}
+
+// shouldMatchBracketAt returns the result of Config.ShouldMatchBracketAt for pos, or true if it is not set.
+func (z *Editor) shouldMatchBracketAt(pos CharPos) bool {
+	if z.Config.ShouldMatchBracketAt == nil {
+		return true
 	}
-	z.Highlight(CharInterval{Start: pos, End: pos})
-	z.Highlight(CharInterval{Start: lpos, End: lpos})
+	return z.Config.ShouldMatchBracketAt(pos)
 }
 
 // FindRune searches one rune forward or backward, using searchFunc and returns the matching rune's position
 // and true, or (0,0) and false. pos is included in the search.
 func (z *Editor) FindRune(pos CharPos, backward bool, searchFunc func(c rune) bool) (CharPos, bool) {
+	return z.findRuneAt(pos, backward, func(c rune, _ CharPos) bool {
+		return searchFunc(c)
+	})
+}
+
+// findRuneAt is like FindRune but the search function also receives the position of the rune.
+func (z *Editor) findRuneAt(pos CharPos, backward bool, searchFunc func(c rune, pos CharPos) bool) (CharPos, bool) {
 	for {
 		c, ok := z.CharAt(pos)
 		if !ok {
 			break
 		}
-		if searchFunc(c) {
+		if searchFunc(c, pos) {
 			return pos, true
 		}
 		if backward {
@@ -1695,25 +3531,28 @@ func (z *Editor) CharAt(pos CharPos) (rune, bool) {
 	if pos.Column > z.LastColumn(pos.Line) {
 		return unicode.ReplacementChar, false
 	}
-	return z.Rows[pos.Line][pos.Column], true
+	return z.row(pos.Line)[pos.Column], true
 }
 
 // RuneAt_Sync safely returns the rune at line, column in a synchronized way. If line and column
-// are out of bounds, the unicode replacement char is returned.
+// are out of bounds, the unicode replacement char is returned. It holds the editor's edit lock, so
+// like Print_Sync it must not be called from input, event, or shortcut handlers or within Do.
 func (z *Editor) RuneAt_Sync(line, column int) rune {
-	z.mutex.RLock()
-	defer z.mutex.RUnlock()
+	z.editMutex.Lock()
+	defer z.editMutex.Unlock()
 	if line < 0 || line >= len(z.Rows) || column < 0 {
 		return unicode.ReplacementChar
 	}
-	if column >= len(z.Rows[line]) {
+	if column >= len(z.row(line)) {
 		return unicode.ReplacementChar
 	}
-	return z.Rows[line][column]
+	return z.row(line)[column]
 }
 
 // MoveCaret moves the caret according to the given movement direction, which may be one of
-// CaretUp, CaretDown, CaretLeft, and CaretRight.
+// CaretUp, CaretDown, CaretLeft, and CaretRight. CaretLineStart and CaretLineEnd move within
+// the displayed (possibly soft-wrapped) row, whereas CaretParagraphStart and CaretParagraphEnd move
+// to the start and end of the paragraph delimited by hard line feeds.
 func (z *Editor) MoveCaret(dir CaretMovement) {
 	drawCaret := z.Config.DrawCaret
 	blinking := z.CaretOff()
@@ -1734,30 +3573,52 @@ func (z *Editor) MoveCaret(dir CaretMovement) {
 		z.maybeHandleWordChangeEvent(z.caretPos)
 	}(oldPos)
 	var newPos CharPos
+	virtual := z.virtualSpace
+	z.virtualSpace = 0
 	switch dir {
 	case CaretDown:
-		newPos = CharPos{Line: min(z.caretPos.Line+1, len(z.Rows)-1), Column: z.caretPos.Column}
+		newLine := z.visibleLine(min(z.caretPos.Line+1, len(z.Rows)-1), true)
+		newPos = CharPos{Line: newLine, Column: min(z.caretPos.Column, z.LastColumn(newLine))}
+		if z.Config.VirtualSpace && z.endsParagraph(newLine) {
+			newPos.Column = min(z.caretPos.Column+virtual, z.LastColumn(newLine))
+			z.virtualSpace = z.caretPos.Column + virtual - newPos.Column
+		}
 		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 		z.caretPos = newPos
-		if z.caretPos.Line == z.lineOffset+z.Lines {
-			z.ScrollDown()
-			return
+		z.scrollToCaretColumn()
+		if z.caretPos.Line > z.lineOffset {
+			z.computeDisplayLines()
+			if _, ok := z.gridRowOf(z.caretPos.Line); !ok {
+				z.scrollDownToDisplay(z.caretPos.Line)
+				return
+			}
 		}
 	case CaretUp:
-		newPos = CharPos{Line: max(z.caretPos.Line-1, 0), Column: z.caretPos.Column}
+		newLine := z.visibleLine(max(z.caretPos.Line-1, 0), false)
+		newPos = CharPos{Line: newLine, Column: min(z.caretPos.Column, z.LastColumn(newLine))}
+		if z.Config.VirtualSpace && z.endsParagraph(newLine) {
+			newPos.Column = min(z.caretPos.Column+virtual, z.LastColumn(newLine))
+			z.virtualSpace = z.caretPos.Column + virtual - newPos.Column
+		}
 		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 		z.caretPos = newPos
+		z.scrollToCaretColumn()
 		if z.caretPos.Line == z.lineOffset-1 {
 			z.ScrollUp()
 			return
 		}
 	case CaretLeft:
+		if virtual > 0 {
+			z.virtualSpace = virtual - 1
+			z.Refresh()
+			return
+		}
 		if z.caretPos.Column == 0 {
 			if z.caretPos.Line == 0 {
 				return
 			}
 			z.MoveCaret(CaretUp)
-			newPos = CharPos{Line: z.caretPos.Line, Column: len(z.Rows[z.caretPos.Line]) - 1}
+			newPos = CharPos{Line: z.caretPos.Line, Column: len(z.row(z.caretPos.Line)) - 1}
 			z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 			z.caretPos = newPos
 			if z.caretPos.Column > z.columnOffset+z.Columns {
@@ -1772,7 +3633,16 @@ func (z *Editor) MoveCaret(dir CaretMovement) {
 			z.ScrollLeft(z.Columns / 2)
 		}
 	case CaretRight:
-		if z.caretPos.Column >= len(z.Rows[z.caretPos.Line])-1 {
+		if z.Config.VirtualSpace && z.caretPos.Column == z.LastColumn(z.caretPos.Line) &&
+			z.endsParagraph(z.caretPos.Line) {
+			z.virtualSpace = virtual + 1
+			if z.caretPos.Column+z.virtualSpace >= z.columnOffset+z.Columns {
+				z.ScrollRight(z.Columns / 2)
+			}
+			z.Refresh()
+			return
+		}
+		if z.caretPos.Column >= len(z.row(z.caretPos.Line))-1 {
 			z.caretPos = CharPos{Line: z.caretPos.Line, Column: 0}
 			z.columnOffset = 0
 			z.MoveCaret(CaretDown)
@@ -1811,36 +3681,183 @@ func (z *Editor) MoveCaret(dir CaretMovement) {
 		}
 	case CaretHalfPageDown:
 		newLine := min(z.LastLine(), z.caretPos.Line+z.Lines/2)
-		newPos = CharPos{Line: newLine, Column: z.caretPos.Column}
+		newPos = CharPos{Line: newLine, Column: min(z.caretPos.Column, z.LastColumn(newLine))}
 		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 		z.caretPos = newPos
+		z.scrollToCaretColumn()
 		if newLine > z.lineOffset+z.Lines-1 {
 			z.CenterLineOnCaret()
 		}
 	case CaretHalfPageUp:
 		newLine := max(0, z.caretPos.Line-z.Lines/2)
-		newPos = CharPos{Line: newLine, Column: z.caretPos.Column}
+		newPos = CharPos{Line: newLine, Column: min(z.caretPos.Column, z.LastColumn(newLine))}
 		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 		z.caretPos = newPos
+		z.scrollToCaretColumn()
 		if newLine < z.lineOffset {
 			z.CenterLineOnCaret()
 		}
 	case CaretPageDown:
 		newLine := min(z.LastLine(), z.caretPos.Line+z.Lines)
-		newPos = CharPos{Line: newLine, Column: z.caretPos.Column}
+		newPos = CharPos{Line: newLine, Column: min(z.caretPos.Column, z.LastColumn(newLine))}
 		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 		z.caretPos = newPos
+		z.scrollToCaretColumn()
 		if newLine > z.lineOffset+z.Lines-1 {
 			z.CenterLineOnCaret()
 		}
 	case CaretPageUp:
 		newLine := max(0, z.caretPos.Line-z.Lines)
-		newPos = CharPos{Line: newLine, Column: z.caretPos.Column}
+		newPos = CharPos{Line: newLine, Column: min(z.caretPos.Column, z.LastColumn(newLine))}
+		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
+		z.caretPos = newPos
+		z.scrollToCaretColumn()
+		if newLine < z.lineOffset {
+			z.CenterLineOnCaret()
+		}
+	case CaretParagraphStart:
+		newLine := z.FindParagraphStart(z.caretPos.Line, z.Config.HardLF)
+		newPos = CharPos{Line: newLine, Column: 0}
 		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
 		z.caretPos = newPos
+		z.columnOffset = 0
 		if newLine < z.lineOffset {
 			z.CenterLineOnCaret()
 		}
+	case CaretParagraphEnd:
+		newLine := z.FindParagraphEnd(z.caretPos.Line, z.Config.HardLF)
+		newPos = CharPos{Line: newLine, Column: z.LastColumn(newLine)}
+		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
+		z.caretPos = newPos
+		z.scrollToCaretColumn()
+		if newLine > z.lineOffset+z.Lines-1 {
+			z.CenterLineOnCaret()
+		}
+	case CaretWordLeft:
+		newPos = z.wordLeftPos(z.caretPos)
+		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
+		z.caretPos = newPos
+		z.scrollToCaret()
+	case CaretWordRight:
+		newPos = z.wordRightPos(z.caretPos)
+		z.handleCaretEvent(CaretLeaveEvent, oldPos, newPos)
+		z.caretPos = newPos
+		z.scrollToCaret()
+	}
+}
+
+// wordLeftPos returns the start of the word left of pos, skipping any non-word runes before pos first.
+// Line feeds are not word runes, so words are found across line breaks.
+func (z *Editor) wordLeftPos(pos CharPos) CharPos {
+	prev, ok := z.PrevPos(pos)
+	for ok {
+		if c, _ := z.CharAt(prev); IsWordRune(c) {
+			break
+		}
+		pos = prev
+		prev, ok = z.PrevPos(pos)
+	}
+	for ok {
+		if c, _ := z.CharAt(prev); !IsWordRune(c) {
+			break
+		}
+		pos = prev
+		prev, ok = z.PrevPos(pos)
+	}
+	return pos
+}
+
+// wordRightPos returns the start of the next word after pos, skipping the rest of the word at pos
+// and the non-word runes after it, or the last position if there is no next word.
+func (z *Editor) wordRightPos(pos CharPos) CharPos {
+	for _, word := range []bool{true, false} {
+		for {
+			if c, ok := z.CharAt(pos); !ok || IsWordRune(c) != word {
+				break
+			}
+			next, ok := z.NextPos(pos)
+			if !ok {
+				return pos
+			}
+			pos = next
+		}
+	}
+	return pos
+}
+
+// DeleteWordLeft deletes from the start of the word left of the caret to the caret, using the same
+// word boundaries as CaretWordLeft. Nothing is done at the start of the text.
+func (z *Editor) DeleteWordLeft() {
+	start := z.wordLeftPos(z.caretPos)
+	end, ok := z.PrevPos(z.caretPos)
+	if z.Config.ReadOnly || !ok || CmpPos(start, z.caretPos) >= 0 {
+		return
+	}
+	z.Delete(CharInterval{Start: start, End: end})
+}
+
+// DeleteWordRight deletes from the caret to the start of the next word, using the same word
+// boundaries as CaretWordRight. Nothing is done at the end of the text.
+func (z *Editor) DeleteWordRight() {
+	end := z.wordRightPos(z.caretPos)
+	if z.Config.ReadOnly || CmpPos(end, z.caretPos) <= 0 {
+		return
+	}
+	if CmpPos(end, z.LastPos()) < 0 {
+		end, _ = z.PrevPos(end)
+	}
+	z.Delete(CharInterval{Start: z.caretPos, End: end})
+}
+
+// RegisterMovement registers a custom caret movement under the given name. The movement function
+// receives the current caret position and returns the new one. An existing movement with the same
+// name is replaced.
+func (z *Editor) RegisterMovement(name string, fn MovementFunc) {
+	z.movements[name] = fn
+}
+
+// RemoveMovement removes the custom caret movement with the given name.
+func (z *Editor) RemoveMovement(name string) {
+	delete(z.movements, name)
+}
+
+// ApplyMovement moves the caret using the custom caret movement registered under the given name,
+// emitting the same events as MoveCaret and scrolling the caret into view. It returns false if no
+// movement is registered under that name.
+func (z *Editor) ApplyMovement(name string) bool {
+	fn, ok := z.movements[name]
+	if !ok || fn == nil {
+		return false
+	}
+	pos := fn(z, z.caretPos)
+	if pos.Line < 0 {
+		pos = CharPos{}
+	}
+	pos.Line = min(pos.Line, z.LastLine())
+	pos.Column = SafePositiveValue(pos.Column, z.LastColumn(pos.Line))
+	z.SetCaret(pos)
+	z.scrollToCaret()
+	return true
+}
+
+// scrollToCaret scrolls the display such that the caret is visible, centering the caret line
+// if it was outside of the display, and refreshes the display.
+func (z *Editor) scrollToCaret() {
+	z.scrollToCaretColumn()
+	if z.caretPos.Line < z.lineOffset || z.caretPos.Line > z.lineOffset+z.Lines-1 {
+		z.CenterLineOnCaret()
+		return
+	}
+	z.Refresh()
+}
+
+// scrollToCaretColumn adjusts the column offset after a vertical caret movement such that
+// the caret column is visible again. It does not refresh the display.
+func (z *Editor) scrollToCaretColumn() {
+	if z.caretPos.Column < z.columnOffset {
+		z.columnOffset = max(0, z.caretPos.Column-z.Columns/2)
+	} else if z.caretPos.Column >= z.columnOffset+z.Columns {
+		z.columnOffset = z.caretPos.Column - z.Columns/2
 	}
 }
 
@@ -1848,14 +3865,34 @@ func (z *Editor) MoveCaret(dir CaretMovement) {
 
 // Insert inserts an array of TextGridCells at row, col, optionally soft wrapping it and using
 // hardLF and softLF as hard and soft line feed characters. The cursor position and tags
-// are updated automatically by this method.
+// are updated automatically by this method. A position after the end of the text is
+// treated as the last position, other invalid positions are ignored and nothing is inserted.
+// This method never panics because of an invalid position, use TryInsert if you need an error.
 func (z *Editor) Insert(r []rune, pos CharPos) {
+	if z.hex != nil {
+		return
+	}
+	z.materialize()
 	if CmpPos(pos, z.LastPos()) > 0 {
 		pos = z.LastPos()
 		z.SetCaret(pos)
 	}
+	if !z.ValidPos(pos) || z.IsProtected(pos) {
+		return
+	}
+	start, r := z.composeInput(r, pos)
+	if start != pos {
+		end, _ := z.PrevPos(pos)
+		z.Delete(CharInterval{Start: start, End: end})
+		pos = start
+	}
+	if z.insertLinked(r, pos) {
+		return
+	}
 	startRow := z.FindParagraphStart(pos.Line, z.Config.HardLF)
 	endRow := z.FindParagraphEnd(pos.Line, z.Config.HardLF)
+	anchors := z.saveAnchors(startRow, endRow)
+	anchorOffset := z.regionOffset(startRow, pos)
 	// endRowLastColumn := len(z.Rows[endRow].Cells) - 1
 	rows := make([][]rune, (endRow-startRow)+1)
 	for i := range rows {
@@ -1916,7 +3953,7 @@ func (z *Editor) Insert(r []rune, pos CharPos) {
 	cline = pos.Line - startRow
 	ccol = pos.Column
 	if z.Config.LineWrap {
-		rows, cline, ccol = z.WordWrapRows(rows, z.Columns, z.Config.SoftWrap, z.Config.HardLF, z.Config.SoftLF,
+		rows, cline, ccol = z.WordWrapRows(rows, z.wrapColumn(), z.Config.SoftWrap, z.Config.HardLF, z.Config.SoftLF,
 			cline, ccol, startRow, tags, pos)
 	}
 	z.caretPos = CharPos{Line: cline + startRow, Column: ccol}
@@ -1935,12 +3972,26 @@ func (z *Editor) Insert(r []rune, pos CharPos) {
 	for i := range rows {
 		z.Rows[i+startRow] = rows[i]
 	}
+	z.restoreAnchors(anchors, startRow, anchorEdit{offset: anchorOffset, inserted: lenInsert})
+	z.highlightEdit(pos.Line)
 
 	// handle events
-	handler, ok := z.eventHandlers[OnChangeEvent]
-	if ok && handler != nil {
-		handler(OnChangeEvent, z)
+	z.markChanged()
+	change := Change{Interval: CharInterval{Start: z.caretPos, End: z.caretPos}, Inserted: lenInsert}
+	if lenInsert > 0 {
+		change.Interval.End = z.advancePos(z.caretPos, lenInsert-1)
+	}
+	z.notifyChange(change)
+}
+
+// TryInsert inserts the given runes at pos like Insert but returns ErrPosOutOfRange
+// if pos is not a valid position in the text.
+func (z *Editor) TryInsert(r []rune, pos CharPos) error {
+	if !z.ValidPos(pos) {
+		return ErrPosOutOfRange
 	}
+	z.Insert(r, pos)
+	return nil
 }
 
 // adjustTagLines adjusts the given tags based on the given lineDelta, which represents the number of lines added
@@ -1971,13 +4022,43 @@ func (z *Editor) adjustTagLines(tags []Tag, lineDelta int, insertPos CharPos) {
 // DELETE with soft wrap
 
 // Delete deletes a range of characters, optionally soft wrapping the paragraph with given hardLF
-// and softLF runes as hard and soft line feed characters.
+// and softLF runes as hard and soft line feed characters. The interval is sanitized first and
+// nothing is deleted if it still contains invalid positions afterwards, so this method never
+// panics because of an invalid interval. Use TryDelete if you need an error.
 func (z *Editor) Delete(fromTo CharInterval) {
-	z.RemoveSelection()
+	if z.hex != nil {
+		return
+	}
+	z.materialize()
 	fromTo = fromTo.Sanitize(z.LastPos())
+	if !z.ValidPos(fromTo.Start) || !z.ValidPos(fromTo.End) || z.isProtectedRange(fromTo) {
+		return
+	}
+	if z.deleteLinked(fromTo) {
+		return
+	}
+	z.RemoveSelection()
 	if CmpPos(fromTo.End, z.LastPos()) == 0 {
 		prev, _ := z.PrevPos(z.LastPos())
 		fromTo.End = prev
+		if CmpPos(fromTo.End, fromTo.Start) < 0 {
+			// only the final line feed was to be deleted, which cannot be removed
+			return
+		}
+	}
+
+	// Anchors are adjusted in the paragraphs affected by the deletion, which include the next one if
+	// its line feed is deleted.
+	anchorStart := z.FindParagraphStart(fromTo.Start.Line, z.Config.HardLF)
+	anchorEnd := z.FindParagraphEnd(fromTo.End.Line, z.Config.HardLF)
+	if fromTo.End.Line == anchorEnd && fromTo.End.Column == z.LastColumn(anchorEnd) && anchorEnd < z.LastLine() {
+		anchorEnd = z.FindParagraphEnd(anchorEnd+1, z.Config.HardLF)
+	}
+	anchors := z.saveAnchors(anchorStart, anchorEnd)
+	anchorDelete := anchorEdit{offset: z.regionOffset(anchorStart, fromTo.Start)}
+	anchorDelete.removed = z.regionOffset(anchorStart, fromTo.End) - anchorDelete.offset
+	if fromTo.End.Column < z.logicalRowLen(fromTo.End.Line) {
+		anchorDelete.removed++ // the last deleted rune is not a soft line feed
 	}
 
 	// We look up the tags starting at or after the deletion start position.
@@ -2002,6 +4083,7 @@ func (z *Editor) Delete(fromTo CharInterval) {
 	}
 
 	rowNumBefore := len(z.Rows)
+	removed := utf8.RuneCountInString(z.GetTextRange(fromTo))
 
 	if fromTo.Start.Line == fromTo.End.Line && fromTo.Start.Column == z.LastColumn(fromTo.Start.Line) {
 		// SPECIAL CASE: The very last char of a line is removed, which must be a line ending delimiter.
@@ -2056,7 +4138,7 @@ func (z *Editor) Delete(fromTo CharInterval) {
 	tags, ok = z.Tags.LookupRange(z.ToEnd(fromTo.Start))
 	newCursorRow := z.caretPos.Line
 	newCursorCol := z.caretPos.Column
-	rows, newCursorRow, newCursorCol = z.WordWrapRows(rows, z.Columns, z.Config.SoftWrap, z.Config.HardLF,
+	rows, newCursorRow, newCursorCol = z.WordWrapRows(rows, z.wrapColumn(), z.Config.SoftWrap, z.Config.HardLF,
 		z.Config.SoftLF, newCursorRow-paraStart, newCursorCol, paraStart, tags, fromTo.Start)
 
 	// Check if we need to delete rows.
@@ -2072,16 +4154,26 @@ func (z *Editor) Delete(fromTo CharInterval) {
 	for i := range rows {
 		z.Rows[i+paraStart] = rows[i]
 	}
+	z.restoreAnchors(anchors, anchorStart, anchorDelete)
 	lineDelta := rowNumBefore - len(z.Rows)
 	z.adjustTagLines(tags, -lineDelta, fromTo.Start)
 	z.SetCaret(CharPos{Line: newCursorRow + paraStart, Column: min(newCursorCol, len(z.Rows[newCursorRow+paraStart])-1)})
+	z.highlightEdit(fromTo.Start.Line)
+	z.markChanged()
 	z.Refresh()
 
 	// handle events
-	handler, ok := z.eventHandlers[OnChangeEvent]
-	if ok && handler != nil {
-		handler(OnChangeEvent, z)
+	z.notifyChange(Change{Interval: CharInterval{Start: z.caretPos, End: z.caretPos}, Removed: removed})
+}
+
+// TryDelete deletes the given interval like Delete but returns ErrPosOutOfRange if
+// the start or end of the interval is not a valid position in the text.
+func (z *Editor) TryDelete(fromTo CharInterval) error {
+	if !z.ValidPos(fromTo.Start) || !z.ValidPos(fromTo.End) {
+		return ErrPosOutOfRange
 	}
+	z.Delete(fromTo)
+	return nil
 }
 
 // ToEnd returns the char interval from the given position to the last char of the buffer.
@@ -2089,14 +4181,44 @@ func (z *Editor) ToEnd(start CharPos) CharInterval {
 	return CharInterval{Start: start, End: z.LastPos()}
 }
 
-// DeleteAll deletes all text.
+// DeleteAll deletes all text. If Config.OnBulkEdit is set and returns false, nothing is deleted.
 func (z *Editor) DeleteAll() {
-	z.Delete(z.ToEnd(CharPos{}))
+	interval := z.ToEnd(CharPos{})
+	if !z.confirmBulkEdit(interval) {
+		return
+	}
+	z.Delete(interval)
+}
+
+// confirmBulkEdit returns false if the interval contains at least Config.BulkEditThreshold runes
+// and Config.OnBulkEdit is set and returns false for it, true otherwise.
+func (z *Editor) confirmBulkEdit(interval CharInterval) bool {
+	if z.Config.OnBulkEdit == nil {
+		return true
+	}
+	n := z.countRunes(interval)
+	if n < z.Config.BulkEditThreshold {
+		return true
+	}
+	return z.Config.OnBulkEdit(interval, n)
+}
+
+// countRunes returns the number of runes in the given interval, including line feeds.
+func (z *Editor) countRunes(interval CharInterval) int {
+	interval = interval.Sanitize(z.LastPos())
+	if interval.Start.Line == interval.End.Line {
+		return interval.End.Column - interval.Start.Column + 1
+	}
+	n := len(z.row(interval.Start.Line)) - interval.Start.Column
+	for i := interval.Start.Line + 1; i < interval.End.Line; i++ {
+		n += len(z.row(i))
+	}
+	return n + interval.End.Column + 1
 }
 
 // LastPos returns the last char position in the buffer.
 func (z *Editor) LastPos() CharPos {
-	return CharPos{Line: len(z.Rows) - 1, Column: len(z.Rows[len(z.Rows)-1]) - 1}
+	return CharPos{Line: len(z.Rows) - 1, Column: z.LastColumn(len(z.Rows) - 1)}
 }
 
 // PrevPos returns the previous char position in the grid and true, or 0, 0 and false if at home position.
@@ -2105,7 +4227,7 @@ func (z *Editor) PrevPos(pos CharPos) (CharPos, bool) {
 		return CharPos{Line: 0, Column: 0}, false
 	}
 	if pos.Column == 0 {
-		return CharPos{Line: pos.Line - 1, Column: len(z.Rows[pos.Line-1]) - 1}, true
+		return CharPos{Line: pos.Line - 1, Column: len(z.row(pos.Line-1)) - 1}, true
 	}
 	return CharPos{Line: pos.Line, Column: pos.Column - 1}, true
 }
@@ -2209,10 +4331,10 @@ func (z *Editor) maybeAdjustTagIntervalForDelete(tag Tag, interval, fromTo CharI
 
 // NextPos returns the next char position in the grid and true, or the last position and false if there is no more.
 func (z *Editor) NextPos(pos CharPos) (CharPos, bool) {
-	if pos.Line >= len(z.Rows)-1 && pos.Column >= len(z.Rows[pos.Line])-1 {
-		return CharPos{Line: len(z.Rows) - 1, Column: len(z.Rows[len(z.Rows)-1]) - 1}, false
+	if pos.Line >= len(z.Rows)-1 && pos.Column >= len(z.row(pos.Line))-1 {
+		return CharPos{Line: len(z.Rows) - 1, Column: len(z.row(len(z.Rows)-1)) - 1}, false
 	}
-	if pos.Column >= len(z.Rows[pos.Line])-1 {
+	if pos.Column >= len(z.row(pos.Line))-1 {
 		return CharPos{Line: pos.Line + 1, Column: 0}, true
 	}
 	return CharPos{Line: pos.Line, Column: pos.Column + 1}, true
@@ -2220,6 +4342,9 @@ func (z *Editor) NextPos(pos CharPos) (CharPos, bool) {
 
 // Backspace deletes the character left of the caret, if there is one.
 func (z *Editor) Backspace() {
+	if z.Config.ReadOnly || z.deleteBlockSelection() || z.backspaceAtCarets() || z.maybeDeleteAutoPair() {
+		return
+	}
 	to := z.caretPos
 	from, changed := z.PrevPos(to)
 
@@ -2232,19 +4357,46 @@ func (z *Editor) Backspace() {
 // Delete1 deletes the character under the caret or the selection, if there is one.
 func (z *Editor) Delete1() {
 	from := z.caretPos
+	if z.Config.ReadOnly || z.deleteBlockSelection() || CmpPos(from, z.LastPos()) >= 0 {
+		return // nothing after the caret
+	}
 	z.Delete(CharInterval{Start: from, End: from}) // char intervals are inclusive on both start and end
-	return
 }
 
 // Return implements the return key behavior, which creates a new line and advances the caret accordingly.
 func (z *Editor) Return() {
+	if z.hex != nil || z.Config.ReadOnly {
+		return
+	}
+	if !z.maybeDeleteSelection() {
+		return
+	}
+	z.fillVirtualSpace()
+	z.insertLineBreak()
+}
+
+// insertLineBreak breaks the paragraph at the caret and puts the caret at the start of the new
+// paragraph. Unlike Return, it also works in read-only mode.
+func (z *Editor) insertLineBreak() {
+	if z.hex != nil || z.IsProtected(z.caretPos) {
+		return
+	}
+	z.materialize()
 	pos := z.caretPos
+	z.markChanged()
 	tags, ok := z.Tags.LookupRange(z.ToEnd(pos))
 	if ok {
 		z.adjustTagLines(tags, 1, pos)
 	}
+	paraStart := z.FindParagraphStart(pos.Line, z.Config.HardLF)
+	anchors := z.saveAnchors(paraStart, z.FindParagraphEnd(pos.Line, z.Config.HardLF))
+	anchorInsert := anchorEdit{offset: z.regionOffset(paraStart, pos), inserted: 1}
+	change := Change{Interval: CharInterval{Start: pos, End: pos}, Inserted: 1}
 	if pos.Column == 0 {
 		z.Rows = slices.Insert(z.Rows, pos.Line, []rune{z.Config.HardLF})
+		z.restoreAnchors(anchors, paraStart, anchorInsert)
+		z.highlightEdit(pos.Line)
+		z.notifyChange(change)
 		z.MoveCaret(CaretDown)
 		z.Refresh()
 		return
@@ -2253,8 +4405,12 @@ func (z *Editor) Return() {
 	z.Rows[pos.Line] = z.Rows[pos.Line][:pos.Column]
 	z.Rows = slices.Insert(z.Rows, pos.Line+1, slices.Clone(buff))
 	z.Rows[pos.Line] = append(z.Rows[pos.Line], z.Config.HardLF)
+	z.restoreAnchors(anchors, paraStart, anchorInsert)
+	z.highlightEdit(pos.Line)
+	z.notifyChange(change)
 	z.Refresh()
 	z.MoveCaret(CaretRight)
+	z.reindentParagraph(z.caretPos.Line)
 }
 
 // READ AND WRITE
@@ -2270,10 +4426,18 @@ type footer struct {
 	CaretLine   int64
 	CaretColumn int64
 	LineOffset  uint64
+	Logical     bool  // since version 101, the following fields are set and used instead of the above
+	CaretPara   int64 // paragraph index of the caret
+	CaretOffset int64 // offset of the caret into its paragraph, not counting line feeds
+	TopPara     int64 // paragraph index of the topmost visible row
+	TopOffset   int64 // offset of the start of the topmost visible row into its paragraph
 }
 
 // SaveTextToFile saves the text as unicode to a file. Nothing else beside the text is saved.
 func (z *Editor) SaveTextToFile(filepath string) error {
+	if z.Config.TrimTrailingWhitespaceOnSave {
+		z.TrimTrailingWhitespace()
+	}
 	z.mutex.Lock()
 	defer z.mutex.Unlock()
 	fi, err := os.OpenFile(filepath, os.O_CREATE|os.O_WRONLY, 0666)
@@ -2281,8 +4445,13 @@ func (z *Editor) SaveTextToFile(filepath string) error {
 		return err
 	}
 	defer fi.Close()
-	_, err = fi.WriteString(z.GetText())
-	return err
+	text := z.GetText()
+	if _, err = fi.WriteString(text); err != nil {
+		return err
+	}
+	z.fileHash = sha256.Sum256([]byte(text))
+	z.modified = false
+	return nil
 }
 
 // LoadTextFromFile loads unicode text from the given file.
@@ -2302,9 +4471,55 @@ func (z *Editor) LoadTextFromFile(filepath string) error {
 	b := &bytes.Buffer{}
 	io.Copy(b, in)
 	z.SetText(b.String())
+	z.fileHash = sha256.Sum256(b.Bytes())
+	z.modified = false
 	return nil
 }
 
+// IsModified returns true if the text has been modified since it was last loaded or saved,
+// false otherwise.
+func (z *Editor) IsModified() bool {
+	return z.modified
+}
+
+// SetModified sets the modification state of the text. This can be used by applications that
+// load and save the text by other means than the methods of the editor.
+func (z *Editor) SetModified(modified bool) {
+	z.modified = modified
+}
+
+// ReloadIfUnmodified reloads the text from the given file if its content differs from the content
+// last loaded from or saved to a file, keeping the caret and the top line where they are. If the file
+// has changed but the text in the editor has been modified, nothing is loaded and ErrModifiedConflict
+// is returned, so the caller can ask the user how to resolve the conflict. The function returns true
+// if the text was reloaded, false otherwise.
+func (z *Editor) ReloadIfUnmodified(filepath string) (bool, error) {
+	fi, err := os.Open(filepath)
+	if err != nil {
+		return false, err
+	}
+	defer fi.Close()
+	in, enc := utfbom.Skip(fi)
+	if !(enc == utfbom.Unknown || enc == utfbom.UTF8) {
+		return false, ErrInvalidStream
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return false, err
+	}
+	hash := sha256.Sum256(b)
+	if hash == z.fileHash {
+		return false, nil
+	}
+	if z.modified {
+		return false, ErrModifiedConflict
+	}
+	z.SetTextPreservingView(string(b))
+	z.fileHash = hash
+	z.modified = false
+	return true, nil
+}
+
 // LoadText loads a UTF8 text from an input stream.
 func (z *Editor) LoadText(in io.Reader) error {
 	z.mutex.Lock()
@@ -2394,6 +4609,9 @@ func (z *Editor) SaveToFile(filepath string) error {
 
 // Save the contents of the editor.
 func (z *Editor) Save(out io.Writer) error {
+	if z.Config.TrimTrailingWhitespaceOnSave {
+		z.TrimTrailingWhitespace()
+	}
 	z.mutex.Lock()
 	defer z.mutex.Unlock()
 	enc := json.NewEncoder(out)
@@ -2430,6 +4648,10 @@ func (z *Editor) saveFooter(enc *json.Encoder) error {
 	f.CaretLine = int64(z.caretPos.Line)
 	f.CaretColumn = int64(z.caretPos.Column)
 	f.LineOffset = uint64(z.lineOffset)
+	para, offset := z.posToParagraphOffset(z.caretPos)
+	f.Logical, f.CaretPara, f.CaretOffset = true, int64(para), int64(offset)
+	para, offset = z.posToParagraphOffset(CharPos{Line: SafePositiveValue(z.lineOffset, z.LastLine()), Column: 0})
+	f.TopPara, f.TopOffset = int64(para), int64(offset)
 	return enc.Encode(f)
 }
 
@@ -2442,15 +4664,24 @@ func (z *Editor) saveText(enc *json.Encoder) error {
 	return nil
 }
 
-// saveTags writes out the tags plus intervals, each one encoded by gob.
+// saveTags writes out the tags plus intervals, each one encoded by gob. Internal tags are not written.
 func (z *Editor) saveTags(enc *json.Encoder) error {
-	allTags := z.Tags.AllTags()
+	allTags := slices.DeleteFunc(z.Tags.AllTags(), func(tag TagWithInterval) bool {
+		return z.isInternalTag(tag.Tag)
+	})
 	if err := enc.Encode(allTags); err != nil {
 		return err
 	}
 	return nil
 }
 
+// isInternalTag returns true if the tag only holds state of the running editor, such as search
+// results, virtual lines, links, folds, auto-paired brackets, and syntax highlighting tokens. The
+// names of such tags start with an underscore, except for search tags.
+func (z *Editor) isInternalTag(tag Tag) bool {
+	return strings.HasPrefix(tag.Name(), "_") || tag.Name() == z.Config.SearchTag.Name()
+}
+
 // LoadFromFile loads the editor contents from the given file.
 func (z *Editor) LoadFromFile(filepath string) error {
 	fi, err := os.Open(filepath)
@@ -2476,6 +4707,7 @@ func (z *Editor) Load(in io.Reader) error {
 		return err
 	}
 	z.Rows = nil
+	z.maxLineLenValid = false
 	if err := z.loadText(dec); err != nil {
 		return err
 	}
@@ -2487,6 +4719,10 @@ func (z *Editor) Load(in io.Reader) error {
 			return err
 		}
 	}
+	if z.Config.LineWrap {
+		// the text was saved with the wrapping of the saving editor
+		z.rewrapAll()
+	}
 	if err := z.loadFooter(dec); err != nil {
 		return err
 	}
@@ -2515,14 +4751,23 @@ func (z *Editor) loadFooter(dec *json.Decoder) error {
 	if err := dec.Decode(&f); err != nil {
 		return err
 	}
-	z.lineOffset = int(f.LineOffset)
-	z.caretPos = CharPos{Line: int(f.CaretLine), Column: int(f.CaretColumn)}
+	if !f.Logical {
+		z.lineOffset = int(f.LineOffset)
+		z.caretPos = CharPos{Line: int(f.CaretLine), Column: int(f.CaretColumn)}
+		return nil
+	}
+	row, _ := z.paragraphStartRow(int(f.CaretPara))
+	z.caretPos = z.paragraphOffsetToPos(row, int(f.CaretOffset))
+	row, _ = z.paragraphStartRow(int(f.TopPara))
+	z.lineOffset = SafePositiveValue(z.paragraphOffsetToPos(row, int(f.TopOffset)).Line, z.maxLineOffset())
 	return nil
 }
 
 // loadText loads the UTF8 text into the editor. Use Load if you want to check versions and
 // headers.
 func (z *Editor) loadText(dec *json.Decoder) error {
+	z.paged = nil
+	z.anchors = make(map[*Anchor]struct{})
 	z.Rows = make([][]rune, 0)
 	if err := dec.Decode(&z.Rows); err != nil {
 		return err
@@ -2542,32 +4787,160 @@ func (z *Editor) loadTags(dec *json.Decoder) error {
 
 // STYLES
 
-// maybeStyleRange styles the given char interval by style insofar as it is within
-// the visible range of the underlying TextGrid (otherwise, nothing is done).
-func (z *Editor) maybeStyleRange(tag Tag, interval CharInterval, styler TagStyleFunc, drawFullLine bool) {
-	if z.currentViewport().OutsideOf(interval) {
+// AddRuleStyler adds a rule styler with the given name, replacing any existing one with the same
+// name. The rule is called for every displayed char of the text on each refresh, so it must be fast.
+// Rule stylers are applied in the order in which they were added, before tag stylers, and do not
+// need to be updated when the text is edited.
+func (z *Editor) AddRuleStyler(name string, rule RuleStyleFunc) {
+	z.shownRows = nil
+	for i := range z.ruleStylers {
+		if z.ruleStylers[i].name == name {
+			z.ruleStylers[i].rule = rule
+			return
+		}
+	}
+	z.ruleStylers = append(z.ruleStylers, ruleStyler{name: name, rule: rule})
+}
+
+// RemoveRuleStyler removes the rule styler with the given name.
+func (z *Editor) RemoveRuleStyler(name string) {
+	z.ruleStylers = slices.DeleteFunc(z.ruleStylers, func(r ruleStyler) bool { return r.name == name })
+	z.shownRows = nil
+}
+
+// applyRuleStylers styles the given grid with all rule stylers. The grid row i displays the text line
+// lines[i] starting at firstColumn, or no text line if lines[i] is negative.
+func (z *Editor) applyRuleStylers(grid *widget.TextGrid, lines []int, firstColumn int) {
+	if len(z.ruleStylers) == 0 {
 		return
 	}
-	for i := range z.Lines {
-		xi := i + z.lineOffset
+	for i := range grid.Rows {
+		if i >= len(lines) || lines[i] < 0 || lines[i] > z.LastLine() {
+			continue
+		}
+		row := z.row(lines[i])
+		for j := range grid.Rows[i].Cells {
+			col := j + firstColumn
+			if col >= len(row) {
+				break
+			}
+			for _, r := range z.ruleStylers {
+				if style, ok := r.rule(CharPos{Line: lines[i], Column: col}, row[col]); ok {
+					grid.Rows[i].Cells[j].Style = style.ToTextGridStyle()
+				}
+			}
+		}
+	}
+}
+
+// highlightTrailingWhitespace styles the spaces and tabs before the hard line feed of each displayed
+// row with the error color. The grid row i displays the text line lines[i] starting at firstColumn, or
+// no text line if lines[i] is negative.
+func (z *Editor) highlightTrailingWhitespace(grid *widget.TextGrid, lines []int, firstColumn int) {
+	style := Style{FGColor: theme.TextColor(), BGColor: theme.ErrorColor()}.ToTextGridStyle()
+	for i := range grid.Rows {
+		if i >= len(lines) || lines[i] < 0 || lines[i] > z.LastLine() {
+			continue
+		}
+		row := z.row(lines[i])
+		last := len(row) - 1
+		if last < 0 || row[last] != z.Config.HardLF {
+			continue
+		}
+		start := last
+		for start > 0 && (row[start-1] == ' ' || row[start-1] == '\t') {
+			start--
+		}
+		for j := max(start, firstColumn); j < last; j++ {
+			if j-firstColumn < len(grid.Rows[i].Cells) {
+				grid.Rows[i].Cells[j-firstColumn].Style = style
+			}
+		}
+	}
+}
+
+// applyStylers styles the given grid with all tag stylers. The grid row i displays the text line lines[i]
+// starting at firstColumn, or no text line if lines[i] is negative.
+func (z *Editor) applyStylers(grid *widget.TextGrid, lines []int, firstColumn int) {
+	stylers := z.Styles.Stylers()
+	if stylers == nil {
+		return
+	}
+	visible, ok := z.styledInterval(lines)
+	if !ok {
+		return
+	}
+	for i := len(stylers) - 1; i >= 0; i-- {
+		tags, ok := z.Tags.TagsByName(stylers[i].TagName)
+		if !ok {
+			continue
+		}
+		loop := tags.Iter()
+		for {
+			tag, ok := loop.Next()
+			if !ok {
+				break
+			}
+			interval, ok := z.Tags.Lookup(tag)
+			if !ok || visible.OutsideOf(interval) {
+				continue
+			}
+			z.maybeStyleRange(grid, lines, firstColumn, tag, interval, stylers[i].StyleFunc, stylers[i].DrawFullLine)
+		}
+	}
+}
+
+// styledInterval returns the char interval from the first to the last of the given text lines, and
+// false if there is no text line among them. Negative lines and lines after the text are skipped.
+func (z *Editor) styledInterval(lines []int) (CharInterval, bool) {
+	first, last := -1, -1
+	for _, line := range lines {
+		if line < 0 || line >= len(z.Rows) {
+			continue
+		}
+		if first < 0 {
+			first = line
+		}
+		last = line
+	}
+	if first < 0 {
+		return CharInterval{}, false
+	}
+	return z.linesInterval(first, last-first+1), true
+}
+
+// maybeStyleRange styles the given char interval by style insofar as it is within
+// the visible range of the given TextGrid (otherwise, nothing is done). The grid row i displays
+// the text line lines[i] starting at firstColumn, or no text line if lines[i] is negative.
+func (z *Editor) maybeStyleRange(grid *widget.TextGrid, lines []int, firstColumn int, tag Tag, interval CharInterval,
+	styler TagStyleFunc, drawFullLine bool) {
+	for i := range grid.Rows {
+		if i >= len(lines) {
+			break
+		}
+		xi := lines[i]
+		if xi < 0 {
+			continue
+		}
 		if xi >= len(z.Rows) {
 			break
 		}
-		for j := range z.Columns {
-			xj := j + z.columnOffset
+		for j := range grid.Rows[i].Cells {
+			xj := j + firstColumn
 			if interval.Contains(CharPos{Line: xi, Column: xj}) {
-				z.grid.Rows[i].Cells[j] = styler(tag, NewCellFromTextGridCell(z.grid.Rows[i].Cells[j])).ToTextGridCell()
+				grid.Rows[i].Cells[j] = styler(tag, NewCellFromTextGridCell(grid.Rows[i].Cells[j])).ToTextGridCell()
 			}
 		}
 	}
 }
 
 func (z *Editor) lineNumberLen() int {
-	s := strconv.Itoa(len(z.Rows))
+	s := strconv.Itoa(max(len(z.Rows)+z.Config.LineNumberStart-1, z.Config.LineNumberStart))
 	return len(s)
 }
 
 func (s *Editor) CreateRenderer() fyne.WidgetRenderer {
+	s.resumeCaretBlinking()
 	return &zgridRenderer{zgrid: s}
 }
 
@@ -2575,18 +4948,24 @@ type zgridRenderer struct {
 	zgrid *Editor
 }
 
-func (r *zgridRenderer) Destroy() {}
+func (r *zgridRenderer) Destroy() {
+	r.zgrid.pauseCaretBlinking()
+}
 
 func (r *zgridRenderer) Layout(size fyne.Size) {
+	r.zgrid.autoSize(size)
 	r.zgrid.background.Resize(size)
+	r.zgrid.hScroll.Resize(fyne.Size{Width: size.Width - theme.ScrollBarSize(), Height: theme.ScrollBarSize()})
+	r.zgrid.hScroll.Move(fyne.Position{X: 0, Y: size.Height - theme.ScrollBarSize()})
 	if !r.zgrid.Config.ShowLineNumbers {
-		r.zgrid.grid.Move(fyne.Position{X: theme.InnerPadding(), Y: theme.InnerPadding()})
+		r.zgrid.gridView().Move(fyne.Position{X: theme.InnerPadding(), Y: theme.InnerPadding()})
 		return
 	}
-	r.zgrid.lineNumberGrid.Move(fyne.Position{X: theme.InnerPadding() / 2,
+	lineNumbers := r.zgrid.lineNumberView()
+	lineNumbers.Move(fyne.Position{X: theme.InnerPadding() / 2,
 		Y: theme.InnerPadding()})
-	r.zgrid.grid.Move(fyne.Position{
-		X: r.zgrid.lineNumberGrid.Position().X + r.zgrid.lineNumberGrid.Size().Width + theme.InnerPadding(),
+	r.zgrid.gridView().Move(fyne.Position{
+		X: lineNumbers.Position().X + lineNumbers.Size().Width + theme.InnerPadding(),
 		Y: theme.InnerPadding(),
 	})
 	r.zgrid.scroll.Resize(fyne.Size{Width: theme.ScrollBarSize(), Height: r.zgrid.background.Size().Height})
@@ -2630,10 +5009,11 @@ func SafePositiveValue(value int, maximum int) int {
 // WORD WRAPPING
 // Ad hoc struct for holding text grid cells plus hosuekeeping info.
 type xCell struct {
-	Rune         rune
-	Row          *[]rune
-	IsCursorCell bool
-	tags         []xTag
+	Rune          rune
+	Row           *[]rune
+	IsCursorCell  bool
+	tags          []xTag
+	noBreakBefore bool // the cell must not start a new line because it is within a non-breaking region
 }
 
 // Ad hoc struct for holding a tag and whether we record the start of the tag's interval
@@ -2650,6 +5030,7 @@ func (z *Editor) WordWrapRows(rows [][]rune, wrapCol int,
 	softWrap bool, hardLF, softLF rune, cursorRow, cursorCol, startRow int,
 	tags []Tag, pos CharPos) ([][]rune, int, int) {
 	para := make([]xCell, 0)
+	noBreaks := z.nonBreakingIntervals(startRow, startRow+len(rows)-1)
 	// 1. push all characters into one array of extended cells
 	// but ignore line breaks
 	cursorToNext := false
@@ -2690,7 +5071,8 @@ func (z *Editor) WordWrapRows(rows [][]rune, wrapCol int,
 						tg = append(tg, xTag{tag: tag, isStart: false})
 					}
 				}
-				para = append(para, xCell{Rune: c, Row: &rows[i], IsCursorCell: isCursor, tags: tg})
+				para = append(para, xCell{Rune: c, Row: &rows[i], IsCursorCell: isCursor, tags: tg,
+					noBreakBefore: withinNonBreaking(noBreaks, CharPos{Line: line, Column: j})})
 			}
 		}
 	}
@@ -2714,13 +5096,26 @@ func (z *Editor) WordWrapRows(rows [][]rune, wrapCol int,
 		line = append(line, c)
 		if unicode.IsSpace(c.Rune) {
 			lastSpc = lpos // space position + 1 because of lpos++
+		} else if len(line) > 1 && z.canBreakBefore(line[len(line)-2].Rune, c.Rune) {
+			lastSpc = lpos - 1 // break before the current char
 		}
 		if lpos >= wrapCol {
 			cutPos := lpos
 			if lastSpc > 0 {
 				cutPos = min(lpos, lastSpc)
 			}
-			if cutPos >= wrapCol/2 && cutPos < len(line) {
+			// move the cut before a non-breaking region unless the region fills the whole line
+			minCut := wrapCol / 2
+			k := cutPos
+			for k > 0 && ((k < len(line) && line[k].noBreakBefore) ||
+				(k == len(line) && i+1 < len(para) && para[i+1].noBreakBefore)) {
+				k--
+			}
+			if k > 0 && k != cutPos {
+				cutPos = k
+				minCut = 1
+			}
+			if cutPos >= minCut && cutPos < len(line) {
 				overflow = make([]xCell, 0, len(line)-cutPos)
 				overflow = append(overflow, line[cutPos:]...)
 				line = line[:cutPos]
@@ -2783,6 +5178,52 @@ func (z *Editor) WordWrapRows(rows [][]rune, wrapCol int,
 	return result, newRow, newCol
 }
 
+// MarkNonBreaking marks the given interval as non-breaking, so word wrapping breaks lines before or
+// after it but not within it unless it is longer than a line. The interval is tracked by the returned
+// tag, which may be deleted from z.Tags to remove the mark. The paragraph is re-wrapped accordingly.
+func (z *Editor) MarkNonBreaking(interval CharInterval) Tag {
+	interval = interval.Sanitize(z.LastPos())
+	tag := z.Tags.CloneTag(NewTag("_nobreak"))
+	z.Tags.Upsert(tag, interval)
+	z.RewrapParagraph(interval.Start.Line)
+	return tag
+}
+
+// nonBreakingIntervals returns the intervals of all non-breaking tags intersecting the given rows.
+func (z *Editor) nonBreakingIntervals(startRow, endRow int) []CharInterval {
+	if set, ok := z.Tags.TagsByName("_nobreak"); !ok || set == nil || set.Size() == 0 {
+		return nil
+	}
+	if startRow < 0 || endRow < startRow || startRow > z.LastLine() {
+		return nil
+	}
+	tags, ok := z.Tags.LookupRange(z.linesInterval(startRow, endRow-startRow+1))
+	if !ok {
+		return nil
+	}
+	var result []CharInterval
+	for _, tag := range tags {
+		if tag == nil || tag.Name() != "_nobreak" {
+			continue
+		}
+		if interval, ok := z.Tags.Lookup(tag); ok {
+			result = append(result, interval)
+		}
+	}
+	return result
+}
+
+// withinNonBreaking returns true if pos is within one of the given intervals but not at its start,
+// i.e., if no line break may occur before pos.
+func withinNonBreaking(intervals []CharInterval, pos CharPos) bool {
+	for _, interval := range intervals {
+		if interval.Contains(pos) && CmpPos(pos, interval.Start) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // adjustTags adjusts the intervals of tags recorded in xCell if necessary.
 // This has bad complexity but note we only recorded start and end positions.
 func (z *Editor) adjustTags(line []xCell, startRow, lineIdx int) {
@@ -2808,6 +5249,104 @@ outer:
 	}
 }
 
+// rewrapParagraphAt word wraps the paragraph starting at startRow anew according to the current
+// configuration, adjusting tags and the caret. The row after the paragraph is returned.
+func (z *Editor) rewrapParagraphAt(startRow int) int {
+	z.materialize()
+	endRow := z.FindParagraphEnd(startRow, z.Config.HardLF)
+	rows := slices.Clone(z.Rows[startRow : endRow+1])
+	wrapCol := z.wrapColumn()
+	if !z.Config.LineWrap {
+		wrapCol = 1
+		for i := range rows {
+			wrapCol += len(rows[i])
+		}
+	}
+	// Tags after the paragraph are not touched by word wrapping, so we need to remember
+	// which ones have to be shifted before their positions are changed.
+	tags, _ := z.Tags.LookupRange(z.ToEnd(CharPos{Line: startRow, Column: 0}))
+	shiftStart := make(map[Tag]bool)
+	shiftEnd := make(map[Tag]bool)
+	for _, tag := range tags {
+		if tag == nil {
+			continue
+		}
+		if interval, ok := z.Tags.Lookup(tag); ok {
+			shiftStart[tag] = interval.Start.Line > endRow
+			shiftEnd[tag] = interval.End.Line > endRow
+		}
+	}
+	cursorRow, cursorCol := -1, -1
+	hasCaret := z.caretPos.Line >= startRow && z.caretPos.Line <= endRow
+	if hasCaret {
+		cursorRow = z.caretPos.Line - startRow
+		cursorCol = z.caretPos.Column
+	}
+	anchors := z.saveAnchors(startRow, endRow)
+	newRows, newRow, newCol := z.WordWrapRows(rows, wrapCol, z.Config.SoftWrap, z.Config.HardLF, z.Config.SoftLF,
+		cursorRow, cursorCol, startRow, tags, CharPos{Line: startRow, Column: 0})
+	lineDelta := len(newRows) - len(rows)
+	z.Rows = slices.Replace(z.Rows, startRow, endRow+1, newRows...)
+	z.restoreAnchors(anchors, startRow, anchorEdit{})
+	if lineDelta != 0 {
+		for _, tag := range tags {
+			if !shiftStart[tag] && !shiftEnd[tag] {
+				continue
+			}
+			interval, ok := z.Tags.Lookup(tag)
+			if !ok {
+				continue
+			}
+			if shiftStart[tag] {
+				interval.Start.Line += lineDelta
+			}
+			if shiftEnd[tag] {
+				interval.End.Line += lineDelta
+			}
+			z.Tags.Upsert(tag, interval)
+		}
+	}
+	if hasCaret {
+		line := startRow + newRow
+		z.caretPos = CharPos{Line: line, Column: SafePositiveValue(newCol, z.LastColumn(line))}
+	} else if z.caretPos.Line > endRow {
+		z.caretPos.Line += lineDelta
+	}
+	return startRow + len(newRows)
+}
+
+// rewrapAll word wraps all paragraphs anew according to the current configuration.
+func (z *Editor) rewrapAll() {
+	if z.paged != nil {
+		return
+	}
+	z.maxLineLenValid = false
+	row := 0
+	for row <= z.LastLine() {
+		row = z.rewrapParagraphAt(row)
+	}
+}
+
+// RewrapParagraph word wraps the paragraph containing the given line anew according to the current
+// configuration, adjusting tags and the caret, and refreshes the display. If line wrapping is off,
+// the paragraph is joined into a single line.
+func (z *Editor) RewrapParagraph(line int) {
+	if line < 0 || line > z.LastLine() {
+		return
+	}
+	z.rewrapParagraphAt(z.FindParagraphStart(line, z.Config.HardLF))
+	z.maxLineLenValid = false
+	z.Refresh()
+}
+
+// RewrapAll word wraps the whole text anew according to the current configuration, adjusting tags
+// and the caret, and refreshes the display. This should be called after changing the wrap settings.
+func (z *Editor) RewrapAll() {
+	z.rewrapAll()
+	z.lineOffset = min(z.lineOffset, z.maxLineOffset())
+	z.SetTopLine(z.lineOffset)
+}
+
 func xCellsToRow(cells []xCell) ([]rune, int) {
 	if len(cells) == 0 {
 		return make([]rune, 0), -1
@@ -2891,6 +5430,27 @@ func IsWordRune(c rune) bool {
 	return true
 }
 
+// IsCJKRune returns true if the rune is a Chinese, Japanese, or Korean ideograph or syllable.
+func IsCJKRune(c rune) bool {
+	return unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// CJKCanBreakBefore is a function that may be used for Config.CanBreakBefore. It allows line breaks
+// between CJK glyphs and follows basic kinsoku rules, i.e., it does not allow breaks before closing
+// punctuation and small kana, or after opening punctuation.
+func CJKCanBreakBefore(prev, next rune) bool {
+	if !(IsCJKRune(prev) || IsCJKRune(next)) {
+		return false
+	}
+	if strings.ContainsRune("、。，．・：；？！ーゝゞヽヾ々)]}）］｝」』】〕〉》〙〗〟’”ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮヵヶ", next) {
+		return false
+	}
+	if strings.ContainsRune("([{（［｛「『【〔〈《〘〖〝‘“", prev) {
+		return false
+	}
+	return true
+}
+
 // IsSymbolRune returns true if the given rune is a symbolic rune, including all kinds of separator
 // and delimiter characters but excluding whitespace and non-graphic glyphs. This function is more liberal
 // than IsWordRune because it includes punctuation.
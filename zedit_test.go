@@ -0,0 +1,165 @@
+package zedit
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// TestMain runs all tests in one test app. Replacing the app resets Fyne's global caches, which
+// must not happen while another editor of the same test may still be drawing.
+func TestMain(m *testing.M) {
+	test.NewApp()
+	os.Exit(m.Run())
+}
+
+// newTestEditor returns an editor with the given number of columns and lines that is displayed in a
+// test canvas. The editor is closed at the end of the test. Its caret does not blink, because Fyne
+// writes a global style when a text grid is refreshed, so a blink loop would race with other editors
+// drawing in the same test; tests of blinking turn it on themselves.
+func newTestEditor(t testing.TB, columns, lines int) *Editor {
+	t.Helper()
+	z := NewEditor(columns, lines, test.NewCanvas())
+	z.Do(func() { z.BlinkCaret(false) })
+	t.Cleanup(z.Close)
+	return z
+}
+
+func TestMaxLineLength(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	check := func(want int) {
+		t.Helper()
+		var got int
+		z.Do(func() { got = z.MaxLineLength() })
+		if got != want {
+			t.Errorf("MaxLineLength() = %d, want %d", got, want)
+		}
+	}
+	z.Do(func() { z.SetText("abc\nabcdefgh\nab") })
+	check(9) // the line feed counts
+
+	// growing a line other than the longest one
+	z.Do(func() { z.Insert([]rune("defghijk"), CharPos{Line: 0, Column: 3}) })
+	check(12)
+
+	// shrinking the longest line
+	z.Do(func() { z.Delete(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 0, Column: 10}}) })
+	check(9)
+	z.Do(func() { z.Delete(CharInterval{Start: CharPos{Line: 1, Column: 2}, End: CharPos{Line: 1, Column: 7}}) })
+	check(3)
+
+	// removing lines
+	z.Do(func() { z.Delete(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 1, Column: 1}}) })
+	check(3)
+	z.Do(func() { z.SetText("") })
+	check(1)
+}
+
+func TestRendererDestroyPausesCaretBlinking(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	z.BlinkCaret(true)
+	r := z.CreateRenderer()
+	r.Destroy()
+	if z.HasBlinkingCaret() {
+		t.Error("caret still blinks after the renderer was destroyed")
+	}
+	if z.isClosed() {
+		t.Fatal("destroying the renderer closed the editor")
+	}
+	z.CreateRenderer()
+	if !z.HasBlinkingCaret() {
+		t.Error("caret blinking was not resumed when the renderer was created again")
+	}
+
+	// blinking that was switched off explicitly stays off
+	z.CreateRenderer().Destroy()
+	z.Do(func() { z.BlinkCaret(false) })
+	z.CreateRenderer()
+	if z.HasBlinkingCaret() {
+		t.Error("caret blinking was resumed after it had been switched off")
+	}
+}
+
+func TestCaretBlinkingDoesNotLeakGoroutines(t *testing.T) {
+	cycle := func() {
+		z := NewEditor(40, 5, test.NewCanvas())
+		for range 10 {
+			z.Do(func() { z.BlinkCaret(true) })
+			z.Do(func() { z.BlinkCaret(false) })
+		}
+		z.Do(func() { z.BlinkCaret(true) })
+		z.Close()
+		z.Close()
+	}
+	cycle() // starts goroutines that are shared by all editors, if any
+	time.Sleep(100 * time.Millisecond)
+	base := runtime.NumGoroutine()
+	for range 20 {
+		cycle()
+	}
+	// goroutines end asynchronously, e.g., delayed refreshes
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(5 * time.Second); n > base && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	if n > base {
+		t.Errorf("%d goroutines after closing 20 editors, want at most %d as before", n, base)
+	}
+}
+
+func TestLoadRewraps(t *testing.T) {
+	wide := newTestEditor(t, 80, 10)
+	text := strings.Repeat("lorem ipsum dolor ", 10) + "sit\namet\n"
+	var buf bytes.Buffer
+	wide.Do(func() {
+		wide.SetText(strings.TrimSuffix(text, "\n"))
+		if err := wide.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	narrow := newTestEditor(t, 20, 10)
+	narrow.Do(func() {
+		if err := narrow.Load(&buf); err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range narrow.Rows {
+			if len(row) > narrow.Columns {
+				t.Errorf("row %d has %d runes after loading, want at most %d", i, len(row), narrow.Columns)
+			}
+		}
+		if got := narrow.Text(); got != text {
+			t.Errorf("Text() = %q after loading, want %q", got, text)
+		}
+	})
+}
+
+func TestSaveSkipsInternalTags(t *testing.T) {
+	z := newTestEditor(t, 80, 10)
+	var buf bytes.Buffer
+	z.Do(func() {
+		z.SetText("abc def ghi")
+		z.Tags.Add(CharInterval{Start: CharPos{Line: 0, Column: 0}, End: CharPos{Line: 0, Column: 2}}, NewTag("user"))
+		z.Tags.Add(CharInterval{Start: CharPos{Line: 0, Column: 4}, End: CharPos{Line: 0, Column: 6}},
+			z.Tags.CloneTag(NewTag("_fold")), z.Tags.CloneTag(z.Config.SearchTag))
+		z.CreateAnchor(CharPos{Line: 0, Column: 8})
+		if err := z.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+	loaded := newTestEditor(t, 80, 10)
+	loaded.Do(func() {
+		if err := loaded.Load(&buf); err != nil {
+			t.Fatal(err)
+		}
+		tags := loaded.Tags.AllTags()
+		if len(tags) != 1 || tags[0].Tag.Name() != "user" {
+			t.Errorf("loaded tags %v, want only the user tag", tags)
+		}
+	})
+}